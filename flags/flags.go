@@ -1,6 +1,11 @@
 package flags
 
 import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
 	"github.com/klaytn/klaytn/cmd/utils"
 	"github.com/urfave/cli/v2"
 	"github.com/urfave/cli/v2/altsrc"
@@ -11,47 +16,325 @@ var (
 		nodeFlags,
 		p2pFlags,
 		rpcFlags,
+		logFlags,
+		peerHistoryFlags,
+		alertFlags,
+	)
+
+	nodeFlags = append([]cli.Flag{withEnvVar(utils.ConfFlag)}, yamlFlags(
+		utils.SrvTypeFlag,
+		utils.DataDirFlag,
+		utils.GenKeyFlag,
+		utils.WriteAddressFlag,
+		utils.BNAddrFlag,
+		utils.NATFlag,
+		utils.NetrestrictFlag,
+		utils.MetricsEnabledFlag,
+		utils.PrometheusExporterFlag,
+		utils.PrometheusExporterPortFlag,
+		utils.AuthorizedNodesFlag,
+		utils.NetworkIdFlag,
+	)...)
+
+	p2pFlags = yamlFlags(
+		utils.CypressFlag,
+		utils.BaobabFlag,
+		DevnetFlag,
+		utils.ListenPortFlag,
+		utils.SubListenPortFlag,
+		utils.MultiChannelUseFlag,
+		utils.MaxConnectionsFlag,
+		utils.MaxRequestContentLengthFlag,
+		utils.MaxPendingPeersFlag,
+		utils.TargetGasLimitFlag,
+		utils.NoDiscoverFlag,
+		utils.RWTimerWaitTimeFlag,
+		utils.RWTimerIntervalFlag,
+		utils.NodeKeyFileFlag,
+		utils.NodeKeyHexFlag,
+		NodeKeystoreFlag,
+		NodeKeystorePasswordFileFlag,
+		RotationPortFlag,
+		AllowedPeerTypesFlag,
+		MaxCNPeersFlag,
+		MaxPNPeersFlag,
+		MaxENPeersFlag,
+		ReservedCNPeersFlag,
 	)
 
-	nodeFlags = []cli.Flag{
-		utils.ConfFlag,
-		altsrc.NewStringFlag(utils.SrvTypeFlag),
-		altsrc.NewPathFlag(utils.DataDirFlag),
-		altsrc.NewStringFlag(utils.GenKeyFlag),
-		altsrc.NewBoolFlag(utils.WriteAddressFlag),
-		altsrc.NewStringFlag(utils.BNAddrFlag),
-		altsrc.NewStringFlag(utils.NATFlag),
-		altsrc.NewStringFlag(utils.NetrestrictFlag),
-		altsrc.NewBoolFlag(utils.MetricsEnabledFlag),
-		altsrc.NewBoolFlag(utils.PrometheusExporterFlag),
-		altsrc.NewIntFlag(utils.PrometheusExporterPortFlag),
-		altsrc.NewStringFlag(utils.AuthorizedNodesFlag),
-		altsrc.NewUint64Flag(utils.NetworkIdFlag),
-	}
-
-	p2pFlags = []cli.Flag{
-		altsrc.NewIntFlag(utils.ListenPortFlag),
-		altsrc.NewIntFlag(utils.SubListenPortFlag),
-		altsrc.NewBoolFlag(utils.MultiChannelUseFlag),
-		altsrc.NewIntFlag(utils.MaxConnectionsFlag),
-		altsrc.NewIntFlag(utils.MaxRequestContentLengthFlag),
-		altsrc.NewIntFlag(utils.MaxPendingPeersFlag),
-		altsrc.NewUint64Flag(utils.TargetGasLimitFlag),
-		altsrc.NewBoolFlag(utils.NoDiscoverFlag),
-		altsrc.NewDurationFlag(utils.RWTimerWaitTimeFlag),
-		altsrc.NewUint64Flag(utils.RWTimerIntervalFlag),
-		altsrc.NewStringFlag(utils.NodeKeyFileFlag),
-		altsrc.NewStringFlag(utils.NodeKeyHexFlag),
-	}
-
-	rpcFlags = []cli.Flag{
-		altsrc.NewBoolFlag(utils.RPCEnabledFlag),
-		altsrc.NewStringFlag(utils.RPCListenAddrFlag),
-		altsrc.NewIntFlag(utils.RPCPortFlag),
-		altsrc.NewStringFlag(utils.RPCApiFlag),
+	rpcFlags = yamlFlags(
+		utils.RPCEnabledFlag,
+		utils.RPCListenAddrFlag,
+		utils.RPCPortFlag,
+		utils.RPCApiFlag,
+	)
+
+	logFlags = yamlFlags(
+		LogFormatFlag,
+		VerbosityFlag,
+		VmoduleFlag,
+		ModuleVerbosityFlag,
+		LogFileFlag,
+		LogFileMaxSizeFlag,
+		LogFileMaxAgeFlag,
+		LogFileMaxBackupsFlag,
+	)
+
+	peerHistoryFlags = yamlFlags(
+		PeerHistoryRetentionFlag,
+		PeerHistoryMaxEntriesFlag,
+	)
+
+	alertFlags = yamlFlags(
+		AlertWebhookFlag,
+		AlertFormatFlag,
+		AlertRoutingKeyFlag,
+		AlertRulesFlag,
+		AlertMinPublicPeersFlag,
+		AlertRetriesFlag,
+		AlertRetryBackoffFlag,
+	)
+)
+
+// Guardian specific flags
+var (
+	NodeKeystoreFlag = &cli.StringFlag{
+		Name:     "nodekeystore",
+		Usage:    "P2P node key as an encrypted keystore file",
+		Aliases:  []string{"p2p.node-keystore"},
+		Category: "NETWORK",
+	}
+	NodeKeystorePasswordFileFlag = &cli.StringFlag{
+		Name:     "nodekeystore.passwordfile",
+		Usage:    "File holding the passphrase of the node keystore (default: the passphrase in $GUARDIAN_NODEKEY_PASSPHRASE)",
+		Aliases:  []string{"p2p.node-keystore-password-file"},
+		Category: "NETWORK",
+	}
+	DevnetFlag = &cli.BoolFlag{
+		Name:     "devnet",
+		Usage:    "Pre-configured local development network",
+		Aliases:  []string{"p2p.devnet"},
+		Category: "NETWORK",
+	}
+	LogFormatFlag = &cli.StringFlag{
+		Name:     "log.format",
+		Usage:    "Log output format: terminal, json or logfmt",
+		Value:    "terminal",
+		Category: "LOGGING AND DEBUGGING",
+	}
+	VerbosityFlag = &cli.IntFlag{
+		Name:     "verbosity",
+		Usage:    "Logging verbosity: 0=crit, 1=error, 2=warn, 3=info, 4=debug, 5=trace",
+		Value:    3,
+		Aliases:  []string{"log.verbosity"},
+		Category: "LOGGING AND DEBUGGING",
+	}
+	VmoduleFlag = &cli.StringFlag{
+		Name:     "vmodule",
+		Usage:    "Per-file verbosity: comma-separated list of <pattern>=<level> (e.g. p2p/*=5)",
+		Aliases:  []string{"log.vmodule"},
+		Category: "LOGGING AND DEBUGGING",
+	}
+	ModuleVerbosityFlag = &cli.StringFlag{
+		Name:     "log.module-verbosity",
+		Usage:    "Per-module verbosity: comma-separated list of <module>=<level> (e.g. node=4)",
+		Category: "LOGGING AND DEBUGGING",
+	}
+	LogFileFlag = &cli.StringFlag{
+		Name:     "log.file",
+		Usage:    "Write logs to the file instead of stderr, rotating it by size",
+		Category: "LOGGING AND DEBUGGING",
+	}
+	LogFileMaxSizeFlag = &cli.IntFlag{
+		Name:     "log.file-max-size",
+		Usage:    "Size in megabytes at which the log file is rotated",
+		Value:    100,
+		Category: "LOGGING AND DEBUGGING",
+	}
+	LogFileMaxAgeFlag = &cli.IntFlag{
+		Name:     "log.file-max-age",
+		Usage:    "Days after which rotated log files are removed (0 = no limit)",
+		Value:    30,
+		Category: "LOGGING AND DEBUGGING",
+	}
+	LogFileMaxBackupsFlag = &cli.IntFlag{
+		Name:     "log.file-max-backups",
+		Usage:    "Number of rotated log files to keep (0 = no limit)",
+		Value:    10,
+		Category: "LOGGING AND DEBUGGING",
+	}
+	AllowedPeerTypesFlag = &cli.StringFlag{
+		Name:     "allowedpeertypes",
+		Usage:    "Comma separated node types public peers may have: cn, pn, en, bn, unknown (default: all)",
+		Aliases:  []string{"p2p.allowed-peer-types"},
+		Category: "NETWORK",
+	}
+	MaxCNPeersFlag = &cli.IntFlag{
+		Name:     "maxcnpeers",
+		Usage:    "Maximum number of public consensus node peers (0 = no limit)",
+		Aliases:  []string{"p2p.max-cn-peers"},
+		Category: "NETWORK",
+	}
+	MaxPNPeersFlag = &cli.IntFlag{
+		Name:     "maxpnpeers",
+		Usage:    "Maximum number of public proxy node peers (0 = no limit)",
+		Aliases:  []string{"p2p.max-pn-peers"},
+		Category: "NETWORK",
+	}
+	MaxENPeersFlag = &cli.IntFlag{
+		Name:     "maxenpeers",
+		Usage:    "Maximum number of public endpoint node peers (0 = no limit)",
+		Aliases:  []string{"p2p.max-en-peers"},
+		Category: "NETWORK",
+	}
+	ReservedCNPeersFlag = &cli.IntFlag{
+		Name:     "reservedcnpeers",
+		Usage:    "Connections of --maxconnections only consensus nodes may use",
+		Aliases:  []string{"p2p.reserved-cn-peers"},
+		Category: "NETWORK",
+	}
+	PeerHistoryRetentionFlag = &cli.DurationFlag{
+		Name:     "peer-history.retention",
+		Usage:    "How long peer connect and disconnect events are kept in the datadir, checked hourly (0 = no limit)",
+		Value:    30 * 24 * time.Hour,
+		Category: "NETWORK",
+	}
+	PeerHistoryMaxEntriesFlag = &cli.IntFlag{
+		Name:     "peer-history.max-entries",
+		Usage:    "Maximum number of peer events kept in the datadir (0 = no limit)",
+		Value:    1000000,
+		Category: "NETWORK",
+	}
+	AlertWebhookFlag = &cli.StringFlag{
+		Name:     "alerts.webhook",
+		Usage:    "URL the alerts are posted to (empty = alerting disabled)",
+		Category: "ALERTS",
+	}
+	AlertFormatFlag = &cli.StringFlag{
+		Name:     "alerts.format",
+		Usage:    "Webhook payload shape: generic, slack or pagerduty",
+		Value:    "generic",
+		Category: "ALERTS",
+	}
+	AlertRoutingKeyFlag = &cli.StringFlag{
+		Name:     "alerts.routing-key",
+		Usage:    "PagerDuty integration key of the pagerduty payload",
+		Category: "ALERTS",
+	}
+	AlertRulesFlag = &cli.StringSliceFlag{
+		Name:     "alerts.rules",
+		Usage:    "Alert rules like \"critical: authorized_peers < 1 for 30s\" or \"peer_disconnects_rate > 2/s\" (none fires in the first minute after the start)",
+		Category: "ALERTS",
+	}
+	AlertMinPublicPeersFlag = &cli.IntFlag{
+		Name:     "alerts.min-public-peers",
+		Usage:    "Alert when fewer public peers are connected, short for the rule \"public_peers < N\" (0 = disabled)",
+		Category: "ALERTS",
+	}
+	AlertRetriesFlag = &cli.IntFlag{
+		Name:     "alerts.retries",
+		Usage:    "Number of times a failed webhook is retried",
+		Value:    5,
+		Category: "ALERTS",
+	}
+	AlertRetryBackoffFlag = &cli.DurationFlag{
+		Name:     "alerts.retry-backoff",
+		Usage:    "Delay before the first retry, doubled on every further retry",
+		Value:    2 * time.Second,
+		Category: "ALERTS",
+	}
+	RotationPortFlag = &cli.IntFlag{
+		Name:     "rotationport",
+		Usage:    "Network listening port of the new identity after a node key rotation, until restart (required for rotation)",
+		Aliases:  []string{"p2p.rotation-port"},
+		Category: "NETWORK",
 	}
 )
 
+// EnvVarPrefix is the prefix of the environment variables setting the
+// GuardianFlags, e.g. GUARDIAN_NODEKEYHEX for --nodekeyhex. A flag given on
+// the command line overrides the environment, which overrides the yaml file.
+// The flags package variables and klaytn's flags do not have these variables,
+// only their copies in GuardianFlags.
+const EnvVarPrefix = "GUARDIAN_"
+
+// EnvVar returns the environment variable of the flag name.
+func EnvVar(name string) string {
+	return EnvVarPrefix + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+// withEnvVar returns a copy of the flag which can also be set by its
+// GUARDIAN_* environment variable. Most flags are klaytn's and shared with
+// other commands, so they are not modified.
+func withEnvVar(f cli.Flag) cli.Flag {
+	env := EnvVar(f.Names()[0])
+	switch f := f.(type) {
+	case *cli.StringFlag:
+		c := *f
+		c.EnvVars = appendEnvVar(f.EnvVars, env)
+		return &c
+	case *cli.PathFlag:
+		c := *f
+		c.EnvVars = appendEnvVar(f.EnvVars, env)
+		return &c
+	case *cli.BoolFlag:
+		c := *f
+		c.EnvVars = appendEnvVar(f.EnvVars, env)
+		return &c
+	case *cli.IntFlag:
+		c := *f
+		c.EnvVars = appendEnvVar(f.EnvVars, env)
+		return &c
+	case *cli.Uint64Flag:
+		c := *f
+		c.EnvVars = appendEnvVar(f.EnvVars, env)
+		return &c
+	case *cli.DurationFlag:
+		c := *f
+		c.EnvVars = appendEnvVar(f.EnvVars, env)
+		return &c
+	case *cli.StringSliceFlag:
+		c := *f
+		c.EnvVars = appendEnvVar(f.EnvVars, env)
+		return &c
+	}
+	panic(fmt.Sprintf("flag %s of type %T can not be set by the environment", f.Names()[0], f))
+}
+
+func appendEnvVar(envs []string, env string) []string {
+	return append(append([]string(nil), envs...), env)
+}
+
+// yamlFlags returns copies of the flags which can be set by their environment
+// variable and by the yaml file.
+func yamlFlags(fs ...cli.Flag) []cli.Flag {
+	ret := make([]cli.Flag, len(fs))
+	for i, f := range fs {
+		switch f := withEnvVar(f).(type) {
+		case *cli.StringFlag:
+			ret[i] = altsrc.NewStringFlag(f)
+		case *cli.PathFlag:
+			ret[i] = altsrc.NewPathFlag(f)
+		case *cli.BoolFlag:
+			ret[i] = altsrc.NewBoolFlag(f)
+		case *cli.IntFlag:
+			ret[i] = altsrc.NewIntFlag(f)
+		case *cli.Uint64Flag:
+			ret[i] = altsrc.NewUint64Flag(f)
+		case *cli.DurationFlag:
+			ret[i] = altsrc.NewDurationFlag(f)
+		case *cli.StringSliceFlag:
+			ret[i] = altsrc.NewStringSliceFlag(f)
+		}
+	}
+	return ret
+}
+
 // Merge merges the given flag slices.
 func Merge(groups ...[]cli.Flag) []cli.Flag {
 	var ret []cli.Flag
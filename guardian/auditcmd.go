@@ -0,0 +1,56 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/klaytn/guardian/node"
+	"github.com/klaytn/klaytn/cmd/utils"
+	"github.com/urfave/cli/v2"
+)
+
+var auditCommand = &cli.Command{
+	Name:     "audit",
+	Usage:    "Inspect the audit log of administrative actions",
+	Category: "MISCELLANEOUS COMMANDS",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "verify",
+			Usage:     "Verify the hash chain of the audit log",
+			ArgsUsage: "[audit log]",
+			Action:    verifyAuditLog,
+			Description: `
+Checks that no entry of the audit log was modified, removed or reordered. The
+log defaults to audit.log in --datadir. The first broken line is reported.`,
+		},
+	},
+}
+
+func verifyAuditLog(ctx *cli.Context) error {
+	path := ctx.Args().First()
+	if path == "" {
+		path = filepath.Join(ctx.String(utils.DataDirFlag.Name), node.DatadirAuditLog)
+	}
+	count, err := node.VerifyAuditLog(path)
+	if err != nil {
+		return fmt.Errorf("audit log %s is broken after %d valid entries: %v", path, count, err)
+	}
+	fmt.Printf("Verified %d entries of %s\n", count, path)
+	return nil
+}
@@ -0,0 +1,463 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klaytn/guardian/flags"
+	"github.com/klaytn/guardian/node"
+	"github.com/klaytn/klaytn/cmd/utils"
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+	"gopkg.in/yaml.v3"
+)
+
+var configCommand = &cli.Command{
+	Name:     "config",
+	Usage:    "Inspect the guardian configuration",
+	Category: "MISCELLANEOUS COMMANDS",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "check",
+			Usage:  "Validate a yaml configuration file",
+			Flags:  []cli.Flag{utils.ConfFlag},
+			Before: inheritFlags,
+			Action: checkConfig,
+			Description: `
+Loads the file given by --conf like the guardian does on startup and reports
+keys the guardian does not read, values of the wrong type and conflicting
+options, with their line numbers.`,
+		},
+		{
+			Name:   "dump",
+			Usage:  "Print the effective configuration",
+			Flags:  []cli.Flag{dumpFormatFlag},
+			Action: dumpConfig,
+			Description: `
+Resolves the configuration like the guardian does on startup and prints every
+value with its source: cli, env, yaml or default. Secrets are redacted. The
+guardian options, including --conf, are given before the command, e.g.
+guardian --conf guardian.yaml config dump.`,
+		},
+	},
+}
+
+var dumpFormatFlag = &cli.StringFlag{
+	Name:  "format",
+	Usage: "Output format: yaml or json",
+	Value: "yaml",
+}
+
+// configDumpEntry is a resolved value printed by config dump.
+type configDumpEntry struct {
+	Flag    string      `json:"flag" yaml:"flag"`
+	YamlKey string      `json:"yamlKey,omitempty" yaml:"yamlKey,omitempty"`
+	Value   interface{} `json:"value" yaml:"value"`
+	Source  string      `json:"source" yaml:"source"`
+}
+
+type severity string
+
+const (
+	severityError   severity = "error"
+	severityWarning severity = "warning"
+)
+
+// configDiagnostic is a problem found in a configuration file.
+type configDiagnostic struct {
+	line     int
+	severity severity
+	msg      string
+}
+
+// yamlEntry is a leaf of the yaml document with its dotted key path.
+type yamlEntry struct {
+	path  string
+	value *yaml.Node
+	line  int
+}
+
+func checkConfig(ctx *cli.Context) error {
+	path := ctx.String(utils.ConfFlag.Name)
+	if path == "" {
+		return errors.New("Use --conf to specify the configuration file")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	diags := checkConfigContent(content)
+	if !hasConfigErrors(diags) {
+		// Finally load the file through the same path as on startup to catch
+		// anything the checks above do not know about.
+		if err := loadConfigFile(ctx); err != nil {
+			diags = append(diags, configDiagnostic{severity: severityError, msg: err.Error()})
+		}
+	}
+
+	for _, d := range diags {
+		if d.line > 0 {
+			fmt.Printf("%s:%d: %s: %s\n", path, d.line, d.severity, d.msg)
+		} else {
+			fmt.Printf("%s: %s: %s\n", path, d.severity, d.msg)
+		}
+	}
+	if hasConfigErrors(diags) {
+		return fmt.Errorf("%s is invalid", path)
+	}
+	fmt.Printf("%s is valid\n", path)
+	return nil
+}
+
+func hasConfigErrors(diags []configDiagnostic) bool {
+	for _, d := range diags {
+		if d.severity == severityError {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfigContent validates the yaml document against flags.GuardianFlags.
+func checkConfigContent(content []byte) []configDiagnostic {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return []configDiagnostic{{severity: severityError, msg: err.Error()}}
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return []configDiagnostic{{line: root.Line, severity: severityError, msg: "configuration must be a mapping"}}
+	}
+
+	known := knownFlags()
+	var (
+		diags   []configDiagnostic
+		entries []yamlEntry
+		used    = make(map[string]bool) // top level sections with at least one known key
+		setBy   = make(map[cli.Flag][]yamlEntry)
+	)
+	collectYamlEntries("", root, known, &entries)
+	for _, e := range entries {
+		f, ok := known[e.path]
+		if !ok {
+			continue
+		}
+		used[strings.SplitN(e.path, ".", 2)[0]] = true
+		setBy[f] = append(setBy[f], e)
+		if err := checkYamlValue(f, e.value); err != nil {
+			diags = append(diags, configDiagnostic{e.line, severityError, fmt.Sprintf("%s: %v", e.path, err)})
+		}
+	}
+
+	// Report unknown keys, folding sections the guardian never reads.
+	reported := make(map[string]bool)
+	for _, e := range entries {
+		if _, ok := known[e.path]; ok {
+			continue
+		}
+		section := strings.SplitN(e.path, ".", 2)[0]
+		switch {
+		case used[section] || section == e.path:
+			diags = append(diags, configDiagnostic{e.line, severityWarning, fmt.Sprintf("unknown key %s is ignored", e.path)})
+		case !reported[section]:
+			reported[section] = true
+			diags = append(diags, configDiagnostic{sectionLine(root, section), severityWarning, fmt.Sprintf("section %s is not read by the guardian", section)})
+		}
+	}
+
+	diags = append(diags, checkConfigConflicts(setBy)...)
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].line < diags[j].line })
+	return diags
+}
+
+// knownFlags maps every name and alias of flags.GuardianFlags to its flag.
+func knownFlags() map[string]cli.Flag {
+	known := make(map[string]cli.Flag)
+	for _, f := range flags.GuardianFlags {
+		for _, name := range f.Names() {
+			known[name] = f
+		}
+	}
+	return known
+}
+
+// collectYamlEntries flattens the mapping into dotted key paths. Mappings
+// which are themselves a known key are not descended into.
+func collectYamlEntries(prefix string, node *yaml.Node, known map[string]cli.Flag, entries *[]yamlEntry) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path := key.Value
+		if prefix != "" {
+			path = prefix + "." + key.Value
+		}
+		if _, ok := known[path]; !ok && value.Kind == yaml.MappingNode {
+			collectYamlEntries(path, value, known, entries)
+			continue
+		}
+		*entries = append(*entries, yamlEntry{path: path, value: value, line: key.Line})
+	}
+}
+
+func sectionLine(root *yaml.Node, section string) int {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == section {
+			return root.Content[i].Line
+		}
+	}
+	return 0
+}
+
+// yamlTags are the yaml tags altsrc accepts for each flag type.
+var yamlTags = map[string]string{
+	"bool":     "!!bool",
+	"int":      "!!int",
+	"uint64":   "!!int",
+	"duration": "!!str",
+	"string":   "!!str",
+}
+
+// checkYamlValue reports whether altsrc can apply the value to the flag.
+func checkYamlValue(f cli.Flag, value *yaml.Node) error {
+	var expected string
+	switch f.(type) {
+	case *altsrc.BoolFlag:
+		expected = "bool"
+	case *altsrc.IntFlag:
+		expected = "int"
+	case *altsrc.Uint64Flag:
+		expected = "uint64"
+	case *altsrc.DurationFlag:
+		expected = "duration"
+	case *altsrc.StringFlag, *altsrc.PathFlag:
+		expected = "string"
+	case *altsrc.StringSliceFlag:
+		return checkYamlList(f, value)
+	default:
+		return fmt.Errorf("can only be given on the command line")
+	}
+
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("expected %s, got a %s", expected, yamlKindName(value.Kind))
+	}
+	if value.Tag == "!!null" {
+		return fmt.Errorf("expected %s, got an empty value (comment the key out instead)", expected)
+	}
+	if value.Tag != yamlTags[expected] {
+		return fmt.Errorf("expected %s, got %s %q", expected, yamlTagName(value.Tag), value.Value)
+	}
+	switch expected {
+	case "int":
+		var i int
+		if err := value.Decode(&i); err != nil {
+			return fmt.Errorf("int %s is out of range", value.Value)
+		}
+	case "uint64":
+		var u uint64
+		if err := value.Decode(&u); err != nil {
+			return fmt.Errorf("uint64 %s is out of range", value.Value)
+		}
+	case "duration":
+		if _, err := time.ParseDuration(value.Value); err != nil {
+			return fmt.Errorf("invalid duration %q, expected a value like \"15s\"", value.Value)
+		}
+	}
+	return nil
+}
+
+// checkYamlList checks a list of strings, parsing the alert rules.
+func checkYamlList(f cli.Flag, value *yaml.Node) error {
+	if value.Kind != yaml.SequenceNode {
+		return fmt.Errorf("expected a list of strings, got a %s", yamlKindName(value.Kind))
+	}
+	for _, item := range value.Content {
+		if item.Kind != yaml.ScalarNode || item.Tag != yamlTags["string"] {
+			return fmt.Errorf("line %d: expected a string", item.Line)
+		}
+		if f.Names()[0] == flags.AlertRulesFlag.Name {
+			if _, err := node.ParseAlertRule(item.Value); err != nil {
+				return fmt.Errorf("line %d: %v", item.Line, err)
+			}
+		}
+	}
+	return nil
+}
+
+func yamlTagName(tag string) string {
+	if tag == "!!str" {
+		return "string"
+	}
+	return strings.TrimPrefix(tag, "!!")
+}
+
+func yamlKindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "sequence"
+	case yaml.AliasNode:
+		return "alias"
+	}
+	return "value"
+}
+
+// checkConfigConflicts reports options which are mutually exclusive.
+func checkConfigConflicts(setBy map[cli.Flag][]yamlEntry) []configDiagnostic {
+	var diags []configDiagnostic
+	exclusive := func(msg string, fs ...cli.Flag) {
+		var set []yamlEntry
+		for _, f := range fs {
+			for _, e := range setBy[f] {
+				if e.value.Tag != "!!null" && e.value.Value != "" && e.value.Value != "false" {
+					set = append(set, e)
+				}
+			}
+		}
+		if len(set) < 2 {
+			return
+		}
+		paths := make([]string, len(set))
+		for i, e := range set {
+			paths[i] = e.path
+		}
+		diags = append(diags, configDiagnostic{set[1].line, severityError, fmt.Sprintf("%s: %s", msg, strings.Join(paths, ", "))})
+	}
+
+	exclusive("node key options are mutually exclusive",
+		flagByName(utils.NodeKeyFileFlag.Name), flagByName(utils.NodeKeyHexFlag.Name),
+		flagByName(flags.NodeKeystoreFlag.Name))
+	exclusive("network presets are mutually exclusive",
+		flagByName(utils.CypressFlag.Name), flagByName(utils.BaobabFlag.Name), flagByName(flags.DevnetFlag.Name))
+	return diags
+}
+
+// flagByName returns the flag of flags.GuardianFlags with the given name.
+func flagByName(name string) cli.Flag {
+	for _, f := range flags.GuardianFlags {
+		for _, n := range f.Names() {
+			if n == name {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+func dumpConfig(ctx *cli.Context) error {
+	cfg := node.NewGuardianConfig(ctx)
+	node.SetIPC(ctx, cfg)
+	node.SetAuthorizedNodes(ctx, cfg)
+	node.SetP2PConfig(ctx, cfg)
+	node.SetNetworkPreset(ctx, cfg)
+
+	items := cfg.Items()
+	entries := make([]configDumpEntry, len(items))
+	for i, item := range items {
+		entries[i] = configDumpEntry{
+			Flag:   item.Flag,
+			Value:  item.Value,
+			Source: flagSource(ctx, item.Flag),
+		}
+		if f := flagByName(item.Flag); f != nil {
+			for _, name := range f.Names() {
+				if strings.Contains(name, ".") {
+					entries[i].YamlKey = name
+					break
+				}
+			}
+		}
+	}
+
+	switch format := ctx.String(dumpFormatFlag.Name); format {
+	case "yaml":
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(entries); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "json":
+		out, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// flagSource tells where the value of the flag came from, following the
+// precedence cli > env > yaml > default.
+func flagSource(ctx *cli.Context, name string) string {
+	f := flagByName(name)
+	switch {
+	case f == nil || !ctx.IsSet(name):
+		return "default"
+	case !preYamlFlags[name]:
+		return "yaml"
+	case fromEnv(ctx, f):
+		return "env"
+	}
+	return "cli"
+}
+
+// fromEnv reports whether the flag has the value of its environment variable,
+// that is the variable is set and the flag is not overridden on the command
+// line.
+func fromEnv(ctx *cli.Context, f cli.Flag) bool {
+	if !f.IsSet() {
+		return false // No environment variable was found
+	}
+	name := f.Names()[0]
+	var env interface{}
+	switch f := f.(type) {
+	case *altsrc.StringFlag:
+		env = f.Value
+	case *altsrc.PathFlag:
+		env = f.Value
+	case *altsrc.BoolFlag:
+		env = f.Value
+	case *altsrc.IntFlag:
+		env = f.Value
+	case *altsrc.Uint64Flag:
+		env = f.Value
+	case *altsrc.DurationFlag:
+		env = f.Value
+	case *altsrc.StringSliceFlag:
+		for _, v := range f.EnvVars {
+			if val, ok := os.LookupEnv(v); ok {
+				return reflect.DeepEqual(ctx.StringSlice(name), node.SplitAndTrim(val))
+			}
+		}
+		return false
+	}
+	return reflect.DeepEqual(ctx.Value(name), env)
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/klaytn/guardian/flags"
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+)
+
+func TestFlagPrecedence(t *testing.T) {
+	const yamlPort, envPort, cliPort = 30001, 30002, 30003
+
+	conf := filepath.Join(t.TempDir(), "guardian.yaml")
+	if err := os.WriteFile(conf, []byte("p2p:\n  port: "+strconv.Itoa(yamlPort)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	port := flagByName("port").(*altsrc.IntFlag)
+	defaultPort := port.Value
+
+	tests := []struct {
+		yaml, env, cli bool
+		want           int
+		source         string
+	}{
+		{false, false, false, defaultPort, "default"},
+		{true, false, false, yamlPort, "yaml"},
+		{false, true, false, envPort, "env"},
+		{true, true, false, envPort, "env"},
+		{false, false, true, cliPort, "cli"},
+		{true, false, true, cliPort, "cli"},
+		{false, true, true, cliPort, "cli"},
+		{true, true, true, cliPort, "cli"},
+	}
+	for i, test := range tests {
+		args := []string{"guardian"}
+		if test.yaml {
+			args = append(args, "--conf", conf)
+		}
+		if test.cli {
+			args = append(args, "--port", strconv.Itoa(cliPort))
+		}
+		if test.env {
+			os.Setenv(flags.EnvVar("port"), strconv.Itoa(envPort))
+		}
+
+		// Applying the flags stores the environment value in them, restore
+		// them for the next run
+		saved := *port.IntFlag
+		preYamlFlags = make(map[string]bool)
+
+		var (
+			got    int
+			source string
+		)
+		app := &cli.App{
+			Flags:  flags.GuardianFlags,
+			Before: before,
+			Action: func(ctx *cli.Context) error {
+				got, source = ctx.Int("port"), flagSource(ctx, "port")
+				return nil
+			},
+		}
+		err := app.Run(args)
+
+		*port.IntFlag = saved
+		os.Unsetenv(flags.EnvVar("port"))
+
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if got != test.want || source != test.source {
+			t.Errorf("test %d: port %d from %s, want %d from %s", i, got, source, test.want, test.source)
+		}
+	}
+}
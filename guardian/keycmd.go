@@ -0,0 +1,378 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/klaytn/guardian/flags"
+	"github.com/klaytn/guardian/node"
+	"github.com/klaytn/klaytn/cmd/utils"
+	"github.com/klaytn/klaytn/crypto"
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+	"github.com/klaytn/klaytn/networks/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	rotationGraceFlag = &cli.DurationFlag{
+		Name:  "grace",
+		Usage: "How long the old identity keeps serving its connections",
+		Value: node.DefaultRotationGracePeriod,
+	}
+	keyFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format: id, kni or json",
+		Value: "kni",
+	}
+	keyHostFlag = &cli.StringFlag{
+		Name:  "host",
+		Usage: "Host used in the printed kni URL",
+		Value: "127.0.0.1",
+	}
+	keyForceFlag = &cli.BoolFlag{
+		Name:  "force",
+		Usage: "Overwrite an existing key file",
+	}
+	keyEncryptFlag = &cli.BoolFlag{
+		Name:  "encrypt",
+		Usage: "Write the key as an encrypted keystore (see --nodekeystore.passwordfile)",
+	}
+	keyPrivateFlag = &cli.BoolFlag{
+		Name:  "private",
+		Usage: "Include the private key in the output",
+	}
+)
+
+// keySourceFlags are the node key options read by the key subcommands.
+var keySourceFlags = []cli.Flag{
+	utils.NodeKeyFileFlag,
+	utils.NodeKeyHexFlag,
+	flags.NodeKeystoreFlag,
+	flags.NodeKeystorePasswordFileFlag,
+}
+
+var keyCommand = &cli.Command{
+	Name:     "key",
+	Usage:    "Manage the guardian node key",
+	Category: "ACCOUNT COMMANDS",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "generate",
+			Usage:     "Generate a new node key",
+			ArgsUsage: "<key file>",
+			Flags: []cli.Flag{
+				keyForceFlag,
+				keyEncryptFlag,
+				flags.NodeKeystorePasswordFileFlag,
+				keyFormatFlag,
+				keyHostFlag,
+			},
+			Before: inheritFlags,
+			Action: generateNodeKey,
+			Description: `
+Generates a new node key and writes it to the key file, as plaintext hex usable
+with --nodekey or, with --encrypt, as a keystore usable with --nodekeystore.
+An existing file is only overwritten with --force.`,
+		},
+		{
+			Name:   "address",
+			Usage:  "Print the identity of the configured node key",
+			Flags:  append(flags.Merge(keySourceFlags), keyFormatFlag, keyHostFlag),
+			Before: inheritFlags,
+			Action: printNodeKeyAddress,
+			Description: `
+Prints the node ID, the kni URL or all of it as JSON for the node key given by
+--nodekey, --nodekeyhex or --nodekeystore.`,
+		},
+		{
+			Name:      "inspect",
+			Usage:     "Print the details of a node key file",
+			ArgsUsage: "[key file]",
+			Flags:     append(flags.Merge(keySourceFlags), keyPrivateFlag, keyFormatFlag, keyHostFlag),
+			Before:    inheritFlags,
+			Action:    inspectNodeKey,
+			Description: `
+Prints the details of a plaintext or keystore key file, or of the configured
+node key if no file is given. The private key is only printed with --private.`,
+		},
+		{
+			Name:      "convert",
+			Usage:     "Convert a plaintext node key into an encrypted keystore",
+			ArgsUsage: "<keystore file>",
+			Flags: []cli.Flag{
+				utils.NodeKeyFileFlag,
+				utils.NodeKeyHexFlag,
+				flags.NodeKeystorePasswordFileFlag,
+				keyForceFlag,
+			},
+			Before: inheritFlags,
+			Action: convertNodeKey,
+			Description: `
+Reads the node key given by --nodekey or --nodekeyhex and writes it to the
+keystore file encrypted with the passphrase from --nodekeystore.passwordfile or
+$` + node.NodeKeyPassphraseEnv + `. Use the keystore with --nodekeystore.`,
+		},
+		{
+			Name:   "rotate",
+			Usage:  "Rotate the node key of a running guardian",
+			Flags:  []cli.Flag{rotationGraceFlag},
+			Action: rotateNodeKey,
+			Description: `
+Asks the guardian listening on the IPC endpoint of --datadir to generate a new
+node key. The new identity dials the authorized nodes and the old identity is
+shut down after the grace period. The new key replaces the configured
+--nodekey or --nodekeystore file, whose old content is kept in a .bak file
+next to it; a --nodekeyhex key can not be rotated.
+
+The guardian must run with --rotationport: the new identity listens on it
+until the guardian is restarted, which moves it back to the configured port.
+The key can be rotated once per run.`,
+		},
+	},
+}
+
+// inheritFlags gives the flags of a subcommand the value they have been given
+// before the subcommand, in the environment or in the yaml file, unless they
+// are given after the subcommand.
+func inheritFlags(ctx *cli.Context) error {
+	lineage := ctx.Lineage()
+	for _, f := range ctx.Command.Flags {
+		name := f.Names()[0]
+		if ctx.IsSet(name) {
+			continue
+		}
+		for _, parent := range lineage[1:] {
+			if parent.IsSet(name) {
+				if err := ctx.Set(name, parent.String(name)); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// readConfiguredNodeKey reads the node key given by the key source flags.
+func readConfiguredNodeKey(ctx *cli.Context) (*ecdsa.PrivateKey, error) {
+	cfg := node.NewGuardianConfig(ctx)
+	switch cfg.CheckCMDState() {
+	case node.NoPrivateKeyPathSpecified:
+		return nil, node.ErrNoNodeKey
+	case node.NodeKeyDuplicated:
+		return nil, node.ErrNodeKeyDuplicated
+	}
+	if err := cfg.ReadNodeKey(); err != nil {
+		return nil, err
+	}
+	return cfg.NodeKey(), nil
+}
+
+// nodeKeyInfo is the printed identity of a node key.
+type nodeKeyInfo struct {
+	ID         string `json:"id"`
+	KNI        string `json:"kni"`
+	Address    string `json:"address"`
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	File       string `json:"file,omitempty"`
+}
+
+func newNodeKeyInfo(ctx *cli.Context, pub *ecdsa.PublicKey) *nodeKeyInfo {
+	id := discover.PubkeyID(pub)
+	port := strconv.Itoa(ctx.Int(utils.ListenPortFlag.Name))
+	return &nodeKeyInfo{
+		ID:        id.String(),
+		KNI:       fmt.Sprintf("kni://%s@%s", id.String(), net.JoinHostPort(ctx.String(keyHostFlag.Name), port)),
+		Address:   crypto.PubkeyToAddress(*pub).Hex(),
+		PublicKey: hex.EncodeToString(crypto.FromECDSAPub(pub)),
+	}
+}
+
+func printNodeKeyInfo(ctx *cli.Context, info *nodeKeyInfo) error {
+	switch format := ctx.String(keyFormatFlag.Name); format {
+	case "id":
+		fmt.Println(info.ID)
+	case "kni":
+		fmt.Println(info.KNI)
+	case "json":
+		out, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	return nil
+}
+
+// checkKeyFile refuses to overwrite an existing file unless --force is given.
+func checkKeyFile(ctx *cli.Context, path string) error {
+	if _, err := os.Stat(path); err == nil && !ctx.Bool(keyForceFlag.Name) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	return nil
+}
+
+func generateNodeKey(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("key file path is required")
+	}
+	out := ctx.Args().First()
+	if err := checkKeyFile(ctx, out); err != nil {
+		return err
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return fmt.Errorf("could not generate key: %v", err)
+	}
+	if ctx.Bool(keyEncryptFlag.Name) {
+		passphrase, err := node.ReadNodeKeyPassphrase(ctx.String(flags.NodeKeystorePasswordFileFlag.Name))
+		if err != nil {
+			return err
+		}
+		err = node.SaveNodeKeystore(out, key, passphrase)
+	} else {
+		err = crypto.SaveECDSA(out, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	info := newNodeKeyInfo(ctx, &key.PublicKey)
+	info.File = out
+	return printNodeKeyInfo(ctx, info)
+}
+
+func printNodeKeyAddress(ctx *cli.Context) error {
+	key, err := readConfiguredNodeKey(ctx)
+	if err != nil {
+		return err
+	}
+	return printNodeKeyInfo(ctx, newNodeKeyInfo(ctx, &key.PublicKey))
+}
+
+func inspectNodeKey(ctx *cli.Context) error {
+	var (
+		key *ecdsa.PrivateKey
+		err error
+	)
+	if ctx.NArg() > 0 {
+		key, err = loadNodeKeyFile(ctx, ctx.Args().First())
+	} else {
+		key, err = readConfiguredNodeKey(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	info := newNodeKeyInfo(ctx, &key.PublicKey)
+	info.File = ctx.Args().First()
+	if ctx.Bool(keyPrivateFlag.Name) {
+		info.PrivateKey = hex.EncodeToString(crypto.FromECDSA(key))
+		if ctx.String(keyFormatFlag.Name) != "json" {
+			fmt.Println(info.PrivateKey)
+		}
+	}
+	return printNodeKeyInfo(ctx, info)
+}
+
+// loadNodeKeyFile reads a plaintext or keystore node key file.
+func loadNodeKeyFile(ctx *cli.Context, path string) (*ecdsa.PrivateKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		return crypto.LoadECDSA(path)
+	}
+	passphrase, err := node.ReadNodeKeyPassphrase(ctx.String(flags.NodeKeystorePasswordFileFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	return node.DecryptNodeKey(content, passphrase)
+}
+
+func convertNodeKey(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("keystore file path is required")
+	}
+	out := ctx.Args().First()
+	if err := checkKeyFile(ctx, out); err != nil {
+		return err
+	}
+
+	key, err := readPlainNodeKey(ctx)
+	if err != nil {
+		return err
+	}
+	passphrase, err := node.ReadNodeKeyPassphrase(ctx.String(flags.NodeKeystorePasswordFileFlag.Name))
+	if err != nil {
+		return err
+	}
+	if err := node.SaveNodeKeystore(out, key, passphrase); err != nil {
+		return err
+	}
+	fmt.Printf("Node key %v encrypted to %s\n", discover.PubkeyID(&key.PublicKey), out)
+	return nil
+}
+
+func readPlainNodeKey(ctx *cli.Context) (*ecdsa.PrivateKey, error) {
+	file, hex := ctx.String(utils.NodeKeyFileFlag.Name), ctx.String(utils.NodeKeyHexFlag.Name)
+	switch {
+	case file != "" && hex != "":
+		return nil, errors.New("Options --nodekey and --nodekeyhex are mutually exclusive")
+	case file != "":
+		return crypto.LoadECDSA(file)
+	case hex != "":
+		return crypto.HexToECDSA(hex)
+	}
+	return nil, errors.New("Use --nodekey or --nodekeyhex to specify a private key")
+}
+
+func rotateNodeKey(ctx *cli.Context) error {
+	cfg := node.NewGuardianConfig(ctx)
+	node.SetIPC(ctx, cfg)
+
+	client, err := rpc.DialIPC(context.Background(), cfg.IPCEndpoint())
+	if err != nil {
+		return fmt.Errorf("failed to attach to the guardian: %v", err)
+	}
+	defer client.Close()
+
+	grace := uint64(ctx.Duration(rotationGraceFlag.Name) / time.Second)
+	var info node.RotationInfo
+	if err := client.Call(&info, "admin_rotateNodeKey", grace); err != nil {
+		return err
+	}
+	fmt.Printf("Old identity: %s\nNew identity: %s\nKey written to %s, old key kept in %s\nOld identity retires at %v\n",
+		info.Old, info.New, info.KeyPath, info.Backup, info.RetiresAt)
+	return nil
+}
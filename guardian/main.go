@@ -21,7 +21,6 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -41,6 +40,9 @@ import (
 var logger = log.NewModuleLogger(log.CMDKCN)
 
 func guardian(ctx *cli.Context) error {
+	if err := node.SetupLogging(ctx); err != nil {
+		return err
+	}
 	cfg := node.NewGuardianConfig(ctx)
 	if err := nodecmd.CheckCommands(ctx); err != nil {
 		return err
@@ -49,17 +51,18 @@ func guardian(ctx *cli.Context) error {
 	node.SetIPC(ctx, cfg)
 	node.SetAuthorizedNodes(ctx, cfg)
 	node.SetP2PConfig(ctx, cfg)
+	node.SetNetworkPreset(ctx, cfg)
 
 	// Check exit condition
 	switch cfg.CheckCMDState() {
 	case node.GenerateNodeKeySpecified:
-		cfg.GenerateNodeKey()
+		return cfg.GenerateNodeKey()
 	case node.NoPrivateKeyPathSpecified:
-		return errors.New("Use --nodekey or --nodekeyhex to specify a private key")
+		return node.ErrNoNodeKey
 	case node.NodeKeyDuplicated:
-		return errors.New("Options --nodekey and --nodekeyhex are mutually exclusive")
+		return node.ErrNodeKeyDuplicated
 	case node.WriteOutAddress:
-		cfg.DoWriteOutAddress()
+		return cfg.DoWriteOutAddress()
 	default:
 		err := cfg.ReadNodeKey()
 		if err != nil {
@@ -105,7 +108,22 @@ func startNode(node *node.Node) error {
 	return nil
 }
 
+// preYamlFlags holds the flags set on the command line or in the environment,
+// before the yaml file is applied. config dump reports their source.
+var preYamlFlags = make(map[string]bool)
+
 func before(ctx *cli.Context) error {
+	for _, f := range flags.GuardianFlags {
+		if name := f.Names()[0]; ctx.IsSet(name) {
+			preYamlFlags[name] = true
+		}
+	}
+	return loadConfigFile(ctx)
+}
+
+// loadConfigFile applies the yaml file given by --conf to the flags which are
+// not set on the command line or in the environment.
+func loadConfigFile(ctx *cli.Context) error {
 	if err := altsrc.InitInputSourceWithContext(
 		flags.GuardianFlags,
 		altsrc.NewYamlSourceFromFlagFunc("conf"),
@@ -125,6 +143,9 @@ func main() {
 	app.Commands = []*cli.Command{
 		nodecmd.VersionCommand,
 		nodecmd.AttachCommand,
+		keyCommand,
+		configCommand,
+		auditCommand,
 	}
 
 	app.Action = guardian
@@ -136,7 +157,7 @@ func main() {
 
 	app.After = func(c *cli.Context) error {
 		debug.Exit()
-		return nil
+		return node.CloseLogging()
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -0,0 +1,298 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/klaytn/klaytn/networks/p2p"
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+// Webhook payload shapes.
+const (
+	AlertFormatGeneric   = "generic"
+	AlertFormatSlack     = "slack"
+	AlertFormatPagerDuty = "pagerduty"
+)
+
+// Alert severities.
+const (
+	AlertCritical = "critical"
+	AlertWarning  = "warning"
+)
+
+const (
+	alertQueueSize   = 64
+	alertPostTimeout = 10 * time.Second
+)
+
+// AlertConfig configures the webhook alerts.
+type AlertConfig struct {
+	Webhook        string        // URL the alerts are posted to, empty = disabled
+	Format         string        // Payload shape: generic, slack or pagerduty
+	RoutingKey     string        // PagerDuty integration key
+	Rules          []string      // Alert rules, see ParseAlertRule
+	MinPublicPeers int           // Shorthand for the rule "public_peers < MinPublicPeers", 0 = disabled
+	Retries        int           // Retries of a failed post
+	RetryBackoff   time.Duration // Delay before the first retry, doubled on each retry
+}
+
+// Alert is a condition reported to the webhook. An alert is sent again with
+// Resolved set once the condition clears. Key tells apart the alerts of the
+// same name, such as the disconnects of different peers.
+type Alert struct {
+	Name     string                 `json:"name"`
+	Key      string                 `json:"key,omitempty"`
+	Severity string                 `json:"severity"`
+	Summary  string                 `json:"summary"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Source   string                 `json:"source"`
+	Time     time.Time              `json:"time"`
+	Resolved bool                   `json:"resolved"`
+}
+
+// Alerter posts alerts to a webhook in the background, retrying failed posts
+// with exponential backoff.
+type Alerter struct {
+	config AlertConfig
+	client *http.Client
+	queue  chan *Alert
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewAlerter validates the configuration and starts posting alerts.
+func NewAlerter(config AlertConfig) (*Alerter, error) {
+	switch config.Format {
+	case AlertFormatGeneric, AlertFormatSlack:
+	case AlertFormatPagerDuty:
+		if config.RoutingKey == "" {
+			return nil, fmt.Errorf("the %s alert format requires a routing key", config.Format)
+		}
+	default:
+		return nil, fmt.Errorf("unknown alert format %q, expected generic, slack or pagerduty", config.Format)
+	}
+	a := &Alerter{
+		config: config,
+		client: &http.Client{Timeout: alertPostTimeout},
+		queue:  make(chan *Alert, alertQueueSize),
+		quit:   make(chan struct{}),
+	}
+	a.wg.Add(1)
+	go a.loop()
+	return a, nil
+}
+
+// Send queues an alert. It never blocks; alerts are dropped when the queue is
+// full because the webhook is unreachable.
+func (a *Alerter) Send(alert *Alert) {
+	if alert.Time.IsZero() {
+		alert.Time = time.Now()
+	}
+	if alert.Source == "" {
+		alert.Source, _ = os.Hostname()
+	}
+	select {
+	case a.queue <- alert:
+	default:
+		logger.Error("Alert queue full, dropping alert", "name", alert.Name, "summary", alert.Summary)
+	}
+}
+
+// Stop stops posting alerts. Queued alerts are dropped.
+func (a *Alerter) Stop() {
+	close(a.quit)
+	a.wg.Wait()
+}
+
+func (a *Alerter) loop() {
+	defer a.wg.Done()
+
+	for {
+		select {
+		case alert := <-a.queue:
+			a.deliver(alert)
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+// deliver posts an alert, retrying with exponential backoff.
+func (a *Alerter) deliver(alert *Alert) {
+	body, err := json.Marshal(a.payload(alert))
+	if err != nil {
+		logger.Error("Failed to encode alert", "name", alert.Name, "err", err)
+		return
+	}
+	backoff := a.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if err = a.post(body); err == nil {
+			logger.Info("Sent alert", "name", alert.Name, "resolved", alert.Resolved)
+			return
+		}
+		if attempt >= a.config.Retries {
+			break
+		}
+		logger.Warn("Failed to send alert, retrying", "name", alert.Name, "err", err, "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-a.quit:
+			return
+		}
+		backoff *= 2
+	}
+	logger.Error("Failed to send alert", "name", alert.Name, "attempts", a.config.Retries+1, "err", err)
+}
+
+func (a *Alerter) post(body []byte) error {
+	resp, err := a.client.Post(a.config.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// payload returns the webhook body of an alert in the configured shape.
+func (a *Alerter) payload(alert *Alert) interface{} {
+	switch a.config.Format {
+	case AlertFormatSlack:
+		state := "FIRING"
+		if alert.Resolved {
+			state = "RESOLVED"
+		}
+		return map[string]interface{}{
+			"text": fmt.Sprintf("[%s] %s (%s) on %s: %s", state, alert.Name, alert.Severity, alert.Source, alert.Summary),
+		}
+	case AlertFormatPagerDuty:
+		action := "trigger"
+		if alert.Resolved {
+			action = "resolve"
+		}
+		dedupKey := alert.Source + "/" + alert.Name
+		if alert.Key != "" {
+			dedupKey += "/" + alert.Key
+		}
+		return map[string]interface{}{
+			"routing_key":  a.config.RoutingKey,
+			"event_action": action,
+			"dedup_key":    dedupKey,
+			"payload": map[string]interface{}{
+				"summary":        alert.Summary,
+				"source":         alert.Source,
+				"severity":       alert.Severity,
+				"timestamp":      alert.Time.Format(time.RFC3339),
+				"component":      "guardian",
+				"custom_details": alert.Details,
+			},
+		}
+	}
+	return alert
+}
+
+// alertMonitor raises an alert whenever an authorized node disconnects and
+// resolves it when the node reconnects. Conditions on metrics are handled by
+// the alert rules instead.
+type alertMonitor struct {
+	alerter *Alerter
+	tracker *peerTracker
+	firing  map[discover.NodeID]bool // Peers whose disconnect alert was not resolved yet
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newAlertMonitor(alerter *Alerter, tracker *peerTracker) *alertMonitor {
+	m := &alertMonitor{
+		alerter: alerter,
+		tracker: tracker,
+		firing:  make(map[discover.NodeID]bool),
+		quit:    make(chan struct{}),
+	}
+	_, events := tracker.subscribe(func(event *GuardianPeerEvent) bool {
+		return event.Side == PeerSideAuthorized &&
+			(event.Type == p2p.PeerEventTypeAdd || event.Type == p2p.PeerEventTypeDrop)
+	})
+	m.wg.Add(1)
+	go m.loop(events)
+	return m
+}
+
+func (m *alertMonitor) loop(events chan *GuardianPeerEvent) {
+	defer m.wg.Done()
+	defer m.tracker.unsubscribe(events)
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if alert := m.alert(event); alert != nil {
+				m.alerter.Send(alert)
+			}
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// alert returns the alert to send for a connect or disconnect event: the
+// loss of the last connection fires the alert of the peer and the next
+// connect resolves it. It returns nil when there is nothing to send.
+func (m *alertMonitor) alert(event *GuardianPeerEvent) *Alert {
+	if event.Connected != m.firing[event.Peer] {
+		return nil
+	}
+	alert := &Alert{
+		Name:     "authorized_peer_disconnected",
+		Key:      event.Peer.String(),
+		Severity: AlertCritical,
+		Summary:  fmt.Sprintf("Authorized node %s disconnected: %s", event.Peer.TerminalString(), event.Error),
+		Details:  map[string]interface{}{"peer": event.Peer.String(), "error": event.Error},
+		Time:     event.Time,
+	}
+	if event.Type == p2p.PeerEventTypeAdd {
+		alert.Resolved = true
+		alert.Summary = fmt.Sprintf("Authorized node %s connected", event.Peer.TerminalString())
+		delete(alert.Details, "error")
+		delete(m.firing, event.Peer)
+	} else {
+		m.firing[event.Peer] = true
+	}
+	return alert
+}
+
+func (m *alertMonitor) stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
@@ -0,0 +1,281 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klaytn/klaytn/networks/p2p"
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+// webhookRecorder is a webhook that fails the first failures posts and
+// records the bodies and times of all of them.
+type webhookRecorder struct {
+	failures int
+
+	lock   sync.Mutex
+	bodies []map[string]interface{}
+	times  []time.Time
+	posted chan struct{}
+}
+
+func newWebhookRecorder(t *testing.T, failures int) (*webhookRecorder, *httptest.Server) {
+	w := &webhookRecorder{failures: failures, posted: make(chan struct{}, 16)}
+	server := httptest.NewServer(http.HandlerFunc(w.serveHTTP))
+	t.Cleanup(server.Close)
+	return w, server
+}
+
+func (w *webhookRecorder) serveHTTP(rw http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+	var body map[string]interface{}
+	json.Unmarshal(data, &body)
+
+	w.lock.Lock()
+	w.bodies = append(w.bodies, body)
+	w.times = append(w.times, time.Now())
+	fail := len(w.bodies) <= w.failures
+	w.lock.Unlock()
+
+	if fail {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+	w.posted <- struct{}{}
+}
+
+// wait waits for n posts and returns the recorded bodies and times.
+func (w *webhookRecorder) wait(t *testing.T, n int) ([]map[string]interface{}, []time.Time) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-w.posted:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("webhook got %d posts, want %d", i, n)
+		}
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.bodies, w.times
+}
+
+func newTestAlerter(t *testing.T, config AlertConfig) *Alerter {
+	alerter, err := NewAlerter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(alerter.Stop)
+	return alerter
+}
+
+func TestAlerterPayloads(t *testing.T) {
+	at := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	alert := func(resolved bool) *Alert {
+		return &Alert{
+			Name:     "authorized_peer_disconnected",
+			Key:      "peer1",
+			Severity: AlertCritical,
+			Summary:  "Authorized node peer1 disconnected",
+			Details:  map[string]interface{}{"peer": "peer1"},
+			Source:   "gdn0",
+			Time:     at,
+			Resolved: resolved,
+		}
+	}
+	tests := []struct {
+		format   string
+		resolved bool
+		want     map[string]interface{}
+	}{
+		{
+			format: AlertFormatGeneric,
+			want: map[string]interface{}{
+				"name":     "authorized_peer_disconnected",
+				"key":      "peer1",
+				"severity": "critical",
+				"summary":  "Authorized node peer1 disconnected",
+				"details":  map[string]interface{}{"peer": "peer1"},
+				"source":   "gdn0",
+				"time":     "2023-07-01T12:00:00Z",
+				"resolved": false,
+			},
+		},
+		{
+			format: AlertFormatSlack,
+			want: map[string]interface{}{
+				"text": "[FIRING] authorized_peer_disconnected (critical) on gdn0: Authorized node peer1 disconnected",
+			},
+		},
+		{
+			format:   AlertFormatSlack,
+			resolved: true,
+			want: map[string]interface{}{
+				"text": "[RESOLVED] authorized_peer_disconnected (critical) on gdn0: Authorized node peer1 disconnected",
+			},
+		},
+		{
+			format: AlertFormatPagerDuty,
+			want: map[string]interface{}{
+				"routing_key":  "key",
+				"event_action": "trigger",
+				"dedup_key":    "gdn0/authorized_peer_disconnected/peer1",
+				"payload": map[string]interface{}{
+					"summary":        "Authorized node peer1 disconnected",
+					"source":         "gdn0",
+					"severity":       "critical",
+					"timestamp":      "2023-07-01T12:00:00Z",
+					"component":      "guardian",
+					"custom_details": map[string]interface{}{"peer": "peer1"},
+				},
+			},
+		},
+		{
+			format:   AlertFormatPagerDuty,
+			resolved: true,
+			want: map[string]interface{}{
+				"routing_key":  "key",
+				"event_action": "resolve",
+				"dedup_key":    "gdn0/authorized_peer_disconnected/peer1",
+				"payload": map[string]interface{}{
+					"summary":        "Authorized node peer1 disconnected",
+					"source":         "gdn0",
+					"severity":       "critical",
+					"timestamp":      "2023-07-01T12:00:00Z",
+					"component":      "guardian",
+					"custom_details": map[string]interface{}{"peer": "peer1"},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		webhook, server := newWebhookRecorder(t, 0)
+		alerter := newTestAlerter(t, AlertConfig{Webhook: server.URL, Format: test.format, RoutingKey: "key"})
+		alerter.Send(alert(test.resolved))
+
+		bodies, _ := webhook.wait(t, 1)
+		if !reflect.DeepEqual(bodies[0], test.want) {
+			t.Errorf("%s (resolved %v): payload mismatch\ngot:  %v\nwant: %v", test.format, test.resolved, bodies[0], test.want)
+		}
+	}
+}
+
+func TestAlerterRetryBackoff(t *testing.T) {
+	const backoff = 50 * time.Millisecond
+
+	webhook, server := newWebhookRecorder(t, 2)
+	alerter := newTestAlerter(t, AlertConfig{Webhook: server.URL, Format: AlertFormatGeneric, Retries: 3, RetryBackoff: backoff})
+	alerter.Send(&Alert{Name: "test"})
+
+	// Two failures are retried after the backoff and twice the backoff
+	_, times := webhook.wait(t, 3)
+	if gap := times[1].Sub(times[0]); gap < backoff {
+		t.Errorf("first retry after %v, want at least %v", gap, backoff)
+	}
+	if gap := times[2].Sub(times[1]); gap < 2*backoff {
+		t.Errorf("second retry after %v, want at least %v", gap, 2*backoff)
+	}
+	// The third post succeeded, so there is no further retry
+	select {
+	case <-webhook.posted:
+		t.Error("alert posted again after a successful post")
+	case <-time.After(8 * backoff):
+	}
+}
+
+func TestAlerterRetryLimit(t *testing.T) {
+	webhook, server := newWebhookRecorder(t, 100)
+	alerter := newTestAlerter(t, AlertConfig{Webhook: server.URL, Format: AlertFormatGeneric, Retries: 1, RetryBackoff: time.Millisecond})
+	alerter.Send(&Alert{Name: "test"})
+
+	webhook.wait(t, 2)
+	select {
+	case <-webhook.posted:
+		t.Error("alert posted more often than 1 + retries")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNewAlerterConfig(t *testing.T) {
+	tests := []struct {
+		config AlertConfig
+		ok     bool
+	}{
+		{AlertConfig{Format: AlertFormatGeneric}, true},
+		{AlertConfig{Format: AlertFormatSlack}, true},
+		{AlertConfig{Format: AlertFormatPagerDuty, RoutingKey: "key"}, true},
+		{AlertConfig{Format: AlertFormatPagerDuty}, false},
+		{AlertConfig{Format: "email"}, false},
+	}
+	for _, test := range tests {
+		alerter, err := NewAlerter(test.config)
+		if (err == nil) != test.ok {
+			t.Errorf("%+v: error %v, want ok %v", test.config, err, test.ok)
+		}
+		if alerter != nil {
+			alerter.Stop()
+		}
+	}
+}
+
+func TestAlertMonitorResolvesOnlyFired(t *testing.T) {
+	peer1, peer2 := discover.NodeID{1}, discover.NodeID{2}
+	tracker := newPeerTracker([]*discover.Node{{ID: peer1}, {ID: peer2}}, nil)
+	_, events := tracker.subscribe(func(*GuardianPeerEvent) bool { return true })
+	m := &alertMonitor{tracker: tracker, firing: make(map[discover.NodeID]bool)}
+
+	tests := []struct {
+		typ      p2p.PeerEventType
+		peer     discover.NodeID
+		send     bool
+		resolved bool
+	}{
+		{p2p.PeerEventTypeAdd, peer1, false, false}, // Initial connect, nothing fired
+		{p2p.PeerEventTypeDrop, peer1, true, false}, // Fires for peer1
+		{p2p.PeerEventTypeAdd, peer2, false, false}, // peer2 never fired
+		{p2p.PeerEventTypeAdd, peer1, true, true},   // Resolves peer1
+		{p2p.PeerEventTypeAdd, peer1, false, false}, // Rotation: connected to the new server too
+		{p2p.PeerEventTypeAdd, peer2, false, false},
+		{p2p.PeerEventTypeDrop, peer1, false, false}, // Retiring server stops, still connected
+		{p2p.PeerEventTypeDrop, peer2, false, false},
+		{p2p.PeerEventTypeDrop, peer2, true, false}, // Last connection lost, fires for peer2
+		{p2p.PeerEventTypeAdd, peer2, true, true},   // Resolves peer2
+	}
+	for i, test := range tests {
+		tracker.handle(&p2p.PeerEvent{Type: test.typ, Peer: test.peer})
+		event := <-events
+
+		alert := m.alert(event)
+		if (alert != nil) != test.send {
+			t.Fatalf("event %d: sent alert %v, want %v", i, alert != nil, test.send)
+		}
+		if alert == nil {
+			continue
+		}
+		if alert.Resolved != test.resolved {
+			t.Errorf("event %d: resolved %v, want %v", i, alert.Resolved, test.resolved)
+		}
+		if alert.Key != test.peer.String() {
+			t.Errorf("event %d: key %q, want the peer id", i, alert.Key)
+		}
+	}
+}
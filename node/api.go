@@ -19,6 +19,7 @@ package node
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/klaytn/klaytn/networks/p2p"
 	"github.com/klaytn/klaytn/networks/p2p/discover"
@@ -38,13 +39,29 @@ func NewPublicGuardianAdminAPI(node *Node) *PublicGuardianAdminAPI {
 }
 
 // Peers retrieves all the information we know about each individual peer at the
-// protocol granularity.
-func (api *PublicGuardianAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
+// protocol granularity. The peers can be filtered by side (authorized or public)
+// and by node type (cn, pn, en, bn).
+func (api *PublicGuardianAdminAPI) Peers(side, nodeType *string) ([]*GuardianPeerInfo, error) {
 	server := api.node.Server()
 	if server == nil {
 		return nil, ErrNodeStopped
 	}
-	return server.PeersInfo(), nil
+	if side != nil && *side != PeerSideAuthorized && *side != PeerSidePublic {
+		return nil, fmt.Errorf("unknown side %q, expected %s or %s", *side, PeerSideAuthorized, PeerSidePublic)
+	}
+
+	peers := api.node.PeersInfo()
+	filtered := peers[:0]
+	for _, peer := range peers {
+		if side != nil && peer.Side != *side {
+			continue
+		}
+		if nodeType != nil && peer.NodeType != *nodeType {
+			continue
+		}
+		filtered = append(filtered, peer)
+	}
+	return filtered, nil
 }
 
 // NodeInfo retrieves all the information we know about the host node at the
@@ -57,6 +74,11 @@ func (api *PublicGuardianAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
 	return server.NodeInfo(), nil
 }
 
+// Alerts returns the alert rules which are pending or firing.
+func (api *PublicGuardianAdminAPI) Alerts() ([]*ActiveAlert, error) {
+	return api.node.Alerts()
+}
+
 // Datadir retrieves the current data directory the node is using.
 func (api *PublicGuardianAdminAPI) Datadir() string {
 	return api.node.DataDir()
@@ -65,7 +87,8 @@ func (api *PublicGuardianAdminAPI) Datadir() string {
 // PrivateAdminAPI is the collection of administrative API methods exposed only
 // over a secure RPC channel.
 type PrivateGuardianAdminAPI struct {
-	node *Node // Node interfaced by this API
+	node      *Node  // Node interfaced by this API
+	transport string // RPC transport the API is served on, recorded in the audit log
 }
 
 // NewPrivateAdminAPI creates a new API definition for the private admin methods
@@ -74,6 +97,11 @@ func NewPrivateGuardianAdminAPI(node *Node) *PrivateGuardianAdminAPI {
 	return &PrivateGuardianAdminAPI{node: node}
 }
 
+// audit records an administrative action together with its outcome.
+func (api *PrivateGuardianAdminAPI) audit(action string, args []interface{}, result interface{}, err error) {
+	api.node.auditAction(api.transport, action, args, result, err)
+}
+
 // addPeerInternal does common part for AddPeer.
 func addPeerInternal(server p2p.Server, url string, onParentChain bool) (*discover.Node, error) {
 	// Try to add the url as a static peer and return
@@ -87,22 +115,141 @@ func addPeerInternal(server p2p.Server, url string, onParentChain bool) (*discov
 
 // AddPeer requests connecting to a remote node, and also maintaining the new
 // connection at all times, even reconnecting if it is lost.
-func (api *PrivateGuardianAdminAPI) AddPeer(url string) (bool, error) {
+func (api *PrivateGuardianAdminAPI) AddPeer(url string) (ok bool, err error) {
+	defer func() { api.audit("admin_addPeer", []interface{}{url}, ok, err) }()
+
 	// Make sure the server is running, fail otherwise
 	server := api.node.Server()
 	if server == nil {
 		return false, ErrNodeStopped
 	}
-	// TODO-Klaytn Refactoring this to check whether the url is valid or not by dialing and return it.
-	if _, err := addPeerInternal(server, url, false); err != nil {
+	// AddPeerAndWait checks whether the url is valid by dialing it.
+	node, err := addPeerInternal(server, url, false)
+	if err != nil {
 		return false, err
-	} else {
-		return true, nil
 	}
+	// Remember the peer so that it is dialed again after a restart
+	if err := api.node.addStaticPeer(node); err != nil {
+		return false, fmt.Errorf("failed to persist static peer: %v", err)
+	}
+	return true, nil
+}
+
+// DefaultAddPeerTimeout is how long AddPeerAndWait waits for the handshake.
+const DefaultAddPeerTimeout = 10 * time.Second
+
+// AddPeerResult is the outcome of AddPeerAndWait.
+type AddPeerResult struct {
+	ID               string   `json:"id"`
+	Connected        bool     `json:"connected"`
+	AlreadyConnected bool     `json:"alreadyConnected,omitempty"`
+	Protocols        []string `json:"protocols"`     // Capabilities of the remote node
+	RTT              string   `json:"rtt,omitempty"` // Duration of the TCP connect, unset if the node dialed in
+}
+
+// AddPeerAndWait is the synchronous variant of AddPeer. It has the p2p server
+// dial the remote node and waits up to timeoutSeconds (10 by default) for the
+// node to be added as a peer. On success the node is kept as a static peer; on
+// failure it is removed again, unless it already was a static peer. The error
+// tells whether the dial or the handshake failed, as soon as it does. The p2p
+// server does not expose why a handshake failed; its debug log has the cause.
+func (api *PrivateGuardianAdminAPI) AddPeerAndWait(url string, timeoutSeconds *uint64) (result *AddPeerResult, err error) {
+	defer func() { api.audit("admin_addPeerAndWait", []interface{}{url, timeoutSeconds}, result, err) }()
+
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kni: %v", err)
+	}
+	timeout := DefaultAddPeerTimeout
+	if timeoutSeconds != nil {
+		if *timeoutSeconds == 0 {
+			return nil, ErrZeroTimeout
+		}
+		timeout = time.Duration(*timeoutSeconds) * time.Second
+	}
+	if restrict := api.node.config.serverConfig.NetRestrict; restrict != nil && !restrict.Contains(node.IP) {
+		return nil, &PeerConnectError{Peer: url, Stage: "dial", Reason: "address not allowed by --netrestrict"}
+	}
+
+	// Subscribe before dialing so that the outcome is not missed
+	events := make(chan *p2p.PeerEvent, 16)
+	sub := server.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+	dials := api.node.dials.watch(node.ID)
+	defer api.node.dials.unwatch(node.ID, dials)
+
+	if info := peerInfo(server, node.ID); info != nil {
+		if err := api.node.addStaticPeer(node); err != nil {
+			return nil, fmt.Errorf("failed to persist static peer: %v", err)
+		}
+		return &AddPeerResult{ID: info.ID, Connected: true, AlreadyConnected: true, Protocols: info.Caps}, nil
+	}
+	wasStatic := containsNode(api.node.StaticPeers(), node.ID)
+	fail := func(err *PeerConnectError) (*AddPeerResult, error) {
+		if !wasStatic {
+			server.RemovePeer(node)
+		}
+		return nil, err
+	}
+	server.AddPeer(node)
+
+	var (
+		rtt    time.Duration
+		dialed bool
+		timer  = time.NewTimer(timeout)
+	)
+	defer timer.Stop()
+	for {
+		select {
+		case outcome := <-dials:
+			if outcome.Err != nil {
+				return fail(&PeerConnectError{Peer: url, Stage: outcome.Stage, Reason: outcome.Err.Error()})
+			}
+			rtt, dialed = outcome.RTT, true
+		case event := <-events:
+			if event.Peer != node.ID || event.Type != p2p.PeerEventTypeAdd {
+				continue
+			}
+			if err := api.node.addStaticPeer(node); err != nil {
+				return nil, fmt.Errorf("failed to persist static peer: %v", err)
+			}
+			result = &AddPeerResult{ID: node.ID.String(), Connected: true}
+			if dialed {
+				result.RTT = rtt.String()
+			}
+			if info := peerInfo(server, node.ID); info != nil {
+				result.Protocols = info.Caps
+			}
+			return result, nil
+		case <-timer.C:
+			if dialed {
+				return fail(&PeerConnectError{Peer: url, Stage: "handshake", Reason: fmt.Sprintf("not completed within %v", timeout), Timeout: true})
+			}
+			return fail(&PeerConnectError{Peer: url, Stage: "dial", Reason: fmt.Sprintf("not completed within %v", timeout), Timeout: true})
+		case <-sub.Err():
+			return nil, ErrNodeStopped
+		}
+	}
+}
+
+// peerInfo returns the information of the connected peer with the given ID.
+func peerInfo(server p2p.Server, id discover.NodeID) *p2p.PeerInfo {
+	for _, info := range server.PeersInfo() {
+		if info.ID == id.String() {
+			return info
+		}
+	}
+	return nil
 }
 
 // RemovePeer disconnects from a a remote node if the connection exists
-func (api *PrivateGuardianAdminAPI) RemovePeer(url string) (bool, error) {
+func (api *PrivateGuardianAdminAPI) RemovePeer(url string) (ok bool, err error) {
+	defer func() { api.audit("admin_removePeer", []interface{}{url}, ok, err) }()
+
 	// Make sure the server is running, fail otherwise
 	server := api.node.Server()
 	if server == nil {
@@ -114,17 +261,195 @@ func (api *PrivateGuardianAdminAPI) RemovePeer(url string) (bool, error) {
 		return false, fmt.Errorf("invalid kni: %v", err)
 	}
 	server.RemovePeer(node)
+	if err := api.node.removeStaticPeer(node); err != nil {
+		return false, fmt.Errorf("failed to persist static peer: %v", err)
+	}
 	return true, nil
 }
 
-// PeerEvents creates an RPC subscription which receives peer events from the
-// node's p2p.Server
-func (api *PrivateGuardianAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
-	// Make sure the server is running, fail otherwise
+// StaticPeers returns the kni URLs of the static peers, both configured and
+// added with AddPeer.
+func (api *PrivateGuardianAdminAPI) StaticPeers() []string {
+	return nodeURLs(api.node.StaticPeers())
+}
+
+// RotateNodeKey replaces the node key with a newly generated one. The old
+// identity keeps its connections for graceSeconds (60 by default) before it is
+// shut down.
+func (api *PrivateGuardianAdminAPI) RotateNodeKey(graceSeconds *uint64) (info *RotationInfo, err error) {
+	defer func() { api.audit("admin_rotateNodeKey", []interface{}{graceSeconds}, info, err) }()
+
+	grace := DefaultRotationGracePeriod
+	if graceSeconds != nil {
+		grace = time.Duration(*graceSeconds) * time.Second
+	}
+	return api.node.RotateNodeKey(grace)
+}
+
+// SetVerbosity changes the global log verbosity (0=crit ... 5=trace).
+func (api *PrivateGuardianAdminAPI) SetVerbosity(level int) (err error) {
+	defer func() { api.audit("admin_setVerbosity", []interface{}{level}, nil, err) }()
+	return SetVerbosity(level)
+}
+
+// SetVmodule changes the per-file log verbosity pattern.
+func (api *PrivateGuardianAdminAPI) SetVmodule(pattern string) (err error) {
+	defer func() { api.audit("admin_setVmodule", []interface{}{pattern}, nil, err) }()
+	return SetVmodule(pattern)
+}
+
+// SetModuleVerbosity changes the log verbosity of a single module.
+func (api *PrivateGuardianAdminAPI) SetModuleVerbosity(module string, level int) (err error) {
+	defer func() { api.audit("admin_setModuleVerbosity", []interface{}{module, level}, nil, err) }()
+	return SetModuleVerbosities(fmt.Sprintf("%s=%d", module, level))
+}
+
+// trustedPeerServer is implemented by p2p servers which can change their set of
+// trusted nodes while running.
+type trustedPeerServer interface {
+	AddTrustedPeer(node *discover.Node)
+	RemoveTrustedPeer(node *discover.Node)
+}
+
+// AddTrustedPeer marks a remote node as trusted, so that it is accepted even
+// when the connection limits are reached. The node is remembered across
+// restarts.
+func (api *PrivateGuardianAdminAPI) AddTrustedPeer(url string) (ok bool, err error) {
+	defer func() { api.audit("admin_addTrustedPeer", []interface{}{url}, ok, err) }()
+
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid kni: %v", err)
+	}
+	if err := api.node.addTrustedPeer(node); err != nil {
+		return false, fmt.Errorf("failed to persist trusted peer: %v", err)
+	}
+	if trusted, ok := server.(trustedPeerServer); ok {
+		trusted.AddTrustedPeer(node)
+	} else {
+		api.node.logger.Warn("Trusted peer takes effect after a restart", "kni", url)
+	}
+	return true, nil
+}
+
+// RemoveTrustedPeer removes a trusted peer added with AddTrustedPeer. It does
+// not disconnect the peer.
+func (api *PrivateGuardianAdminAPI) RemoveTrustedPeer(url string) (ok bool, err error) {
+	defer func() { api.audit("admin_removeTrustedPeer", []interface{}{url}, ok, err) }()
+
 	server := api.node.Server()
 	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid kni: %v", err)
+	}
+	if err := api.node.removeTrustedPeer(node); err != nil {
+		return false, fmt.Errorf("failed to persist trusted peer: %v", err)
+	}
+	if trusted, ok := server.(trustedPeerServer); ok {
+		trusted.RemoveTrustedPeer(node)
+	} else {
+		api.node.logger.Warn("Trusted peer removal takes effect after a restart", "kni", url)
+	}
+	return true, nil
+}
+
+// TrustedPeers returns the kni URLs of the trusted peers: the configured ones,
+// the authorized nodes and those added with AddTrustedPeer.
+func (api *PrivateGuardianAdminAPI) TrustedPeers() []string {
+	return nodeURLs(api.node.TrustedPeers())
+}
+
+// PeerHistory returns the peer connects and disconnects stored in the datadir
+// between from (the oldest event by default) and to (now by default),
+// optionally only of the peer with the given node ID. At most 10000 events are
+// returned; narrow the range to see more.
+func (api *PrivateGuardianAdminAPI) PeerHistory(from, to *time.Time, peer *string) ([]*GuardianPeerEvent, error) {
+	start, end := time.Unix(0, 0), time.Now()
+	if from != nil {
+		start = *from
+	}
+	if to != nil {
+		end = *to
+	}
+	var id *discover.NodeID
+	if peer != nil && *peer != "" {
+		parsed, err := discover.HexID(*peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer id: %v", err)
+		}
+		id = &parsed
+	}
+	return api.node.PeerHistory(start, end, id)
+}
+
+// PeerEventFilter selects the events sent to a PeerEvents subscriber.
+type PeerEventFilter struct {
+	Types  []p2p.PeerEventType `json:"types"`  // Event types, add and drop by default
+	Peer   string              `json:"peer"`   // Node ID of a single peer
+	Side   string              `json:"side"`   // authorized or public
+	Replay int                 `json:"replay"` // Number of recent add and drop events sent first
+}
+
+// matcher validates the filter and returns a function testing events against it.
+func (f *PeerEventFilter) matcher() (func(*GuardianPeerEvent) bool, error) {
+	types := map[p2p.PeerEventType]bool{p2p.PeerEventTypeAdd: true, p2p.PeerEventTypeDrop: true}
+	if len(f.Types) > 0 {
+		types = make(map[p2p.PeerEventType]bool, len(f.Types))
+		for _, t := range f.Types {
+			switch t {
+			case p2p.PeerEventTypeAdd, p2p.PeerEventTypeDrop, p2p.PeerEventTypeMsgSend, p2p.PeerEventTypeMsgRecv:
+				types[t] = true
+			default:
+				return nil, fmt.Errorf("unknown peer event type %q", t)
+			}
+		}
+	}
+	var peer *discover.NodeID
+	if f.Peer != "" {
+		id, err := discover.HexID(f.Peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer id: %v", err)
+		}
+		peer = &id
+	}
+	if f.Side != "" && f.Side != PeerSideAuthorized && f.Side != PeerSidePublic {
+		return nil, fmt.Errorf("unknown side %q, expected %s or %s", f.Side, PeerSideAuthorized, PeerSidePublic)
+	}
+	if f.Replay < 0 {
+		return nil, fmt.Errorf("negative replay count %d", f.Replay)
+	}
+
+	return func(event *GuardianPeerEvent) bool {
+		return types[event.Type] &&
+			(peer == nil || event.Peer == *peer) &&
+			(f.Side == "" || event.Side == f.Side)
+	}, nil
+}
+
+// PeerEvents creates an RPC subscription which receives peer events from the
+// node's p2p.Server. Without a filter only connects and disconnects are sent.
+// With filter.Replay set, up to that many recent matching connects and
+// disconnects are sent before the live events.
+func (api *PrivateGuardianAdminAPI) PeerEvents(ctx context.Context, filter *PeerEventFilter) (*rpc.Subscription, error) {
+	// Make sure the server is running, fail otherwise
+	tracker := api.node.peerTracker()
+	if tracker == nil {
 		return nil, ErrNodeStopped
 	}
+	if filter == nil {
+		filter = &PeerEventFilter{}
+	}
+	match, err := filter.matcher()
+	if err != nil {
+		return nil, err
+	}
 
 	// Create the subscription
 	notifier, supported := rpc.NotifierFromContext(ctx)
@@ -133,17 +458,24 @@ func (api *PrivateGuardianAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscr
 	}
 	rpcSub := notifier.CreateSubscription()
 
+	replay, events := tracker.subscribe(match)
+	if len(replay) > filter.Replay {
+		replay = replay[len(replay)-filter.Replay:]
+	}
+
 	go func() {
-		events := make(chan *p2p.PeerEvent)
-		sub := server.SubscribeEvents(events)
-		defer sub.Unsubscribe()
+		defer tracker.unsubscribe(events)
 
+		for _, event := range replay {
+			notifier.Notify(rpcSub.ID, event)
+		}
 		for {
 			select {
-			case event := <-events:
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
 				notifier.Notify(rpcSub.ID, event)
-			case <-sub.Err():
-				return
 			case <-rpcSub.Err():
 				return
 			case <-notifier.Closed():
@@ -0,0 +1,207 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DatadirAuditLog is the audit log file name in the data directory.
+const DatadirAuditLog = "audit.log"
+
+// auditGenesisHash is the previous hash of the first audit entry.
+var auditGenesisHash = strings.Repeat("0", 2*sha256.Size)
+
+// Transports an administrative action can be requested over.
+const (
+	TransportInProc = "inproc"
+	TransportIPC    = "ipc"
+)
+
+// AuditEntry is a single line of the audit log. Hash is the SHA-256 of the
+// JSON encoding of the entry with an empty Hash, and PrevHash links it to the
+// previous entry, so that modifying or removing an entry breaks the chain.
+type AuditEntry struct {
+	Seq       uint64          `json:"seq"`
+	Time      time.Time       `json:"time"`
+	Transport string          `json:"transport"`
+	Action    string          `json:"action"`
+	Args      json.RawMessage `json:"args,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	PrevHash  string          `json:"prevHash"`
+	Hash      string          `json:"hash"`
+}
+
+func (e *AuditEntry) computeHash() (string, error) {
+	unhashed := *e
+	unhashed.Hash = ""
+	enc, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(enc)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditLog appends administrative actions to a hash chained JSON lines file.
+type AuditLog struct {
+	file *os.File
+	seq  uint64
+	last string // Hash of the last entry
+	lock sync.Mutex
+}
+
+// OpenAuditLog opens the audit log at path for appending, continuing the chain
+// of the existing entries. An entry torn by a crash while it was written is
+// dropped; a last entry which does not match its hash is an error.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	a := &AuditLog{last: auditGenesisHash}
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	// Entries are written with their newline at once, so a line without one
+	// is a torn write which is not part of the chain.
+	if end := bytes.LastIndexByte(content, '\n') + 1; end < len(content) {
+		logger.Warn("Dropping torn audit log entry", "path", path, "bytes", len(content)-end)
+		if err := os.Truncate(path, int64(end)); err != nil {
+			return nil, err
+		}
+		content = content[:end]
+	}
+	if content = bytes.TrimSpace(content); len(content) > 0 {
+		var entry AuditEntry
+		if err := json.Unmarshal(content[bytes.LastIndexByte(content, '\n')+1:], &entry); err != nil {
+			return nil, fmt.Errorf("corrupt audit log %s: last entry: %v", path, err)
+		}
+		if hash, err := entry.computeHash(); err != nil || hash != entry.Hash {
+			return nil, fmt.Errorf("corrupt audit log %s: last entry does not match its hash, see 'guardian audit verify'", path)
+		}
+		a.seq, a.last = entry.Seq+1, entry.Hash
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	a.file = file
+	return a, nil
+}
+
+// Append records an action with its arguments and outcome.
+func (a *AuditLog) Append(transport, action string, args []interface{}, result interface{}, actionErr error) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	entry := AuditEntry{
+		Seq:       a.seq,
+		Time:      time.Now().UTC(),
+		Transport: transport,
+		Action:    action,
+		PrevHash:  a.last,
+	}
+	var err error
+	if args != nil {
+		if entry.Args, err = json.Marshal(args); err != nil {
+			return err
+		}
+	}
+	if result != nil {
+		if entry.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+	if actionErr != nil {
+		entry.Error = actionErr.Error()
+	}
+	if entry.Hash, err = entry.computeHash(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(&entry)
+	if err != nil {
+		return err
+	}
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := a.file.Sync(); err != nil {
+		return err
+	}
+	a.seq, a.last = entry.Seq+1, entry.Hash
+	return nil
+}
+
+// Close closes the audit log file.
+func (a *AuditLog) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.file.Close()
+}
+
+// VerifyAuditLog checks the hash chain of the audit log at path and returns
+// the number of valid entries. The error names the first broken line.
+func VerifyAuditLog(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var (
+		scanner = bufio.NewScanner(file)
+		prev    = auditGenesisHash
+		count   int
+	)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return count, fmt.Errorf("line %d: malformed entry: %v", line, err)
+		}
+		if entry.Seq != uint64(count) {
+			return count, fmt.Errorf("line %d: sequence %d, expected %d", line, entry.Seq, count)
+		}
+		if entry.PrevHash != prev {
+			return count, fmt.Errorf("line %d: previous hash does not match the preceding entry", line)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return count, fmt.Errorf("line %d: %v", line, err)
+		}
+		if hash != entry.Hash {
+			return count, fmt.Errorf("line %d: entry hash mismatch, the entry was modified", line)
+		}
+		prev = entry.Hash
+		count++
+	}
+	return count, scanner.Err()
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeAuditLog writes an audit log with n entries and returns its path.
+func writeAuditLog(t *testing.T, n int) string {
+	path := filepath.Join(t.TempDir(), DatadirAuditLog)
+	log, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		var actionErr error
+		if i%2 == 1 {
+			actionErr = errors.New("failed")
+		}
+		if err := log.Append(TransportIPC, "admin_addPeer", []interface{}{"kni", i}, i%2 == 0, actionErr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readAuditLines(t *testing.T, path string) [][]byte {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	return lines[:len(lines)-1] // Empty after the last newline
+}
+
+func writeAuditLines(t *testing.T, path string, lines [][]byte) {
+	if err := os.WriteFile(path, bytes.Join(lines, nil), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditLogChain(t *testing.T) {
+	path := writeAuditLog(t, 3)
+
+	// Reopening continues the chain
+	log, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append(TransportInProc, "admin_removePeer", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	if count, err := VerifyAuditLog(path); err != nil || count != 4 {
+		t.Errorf("verify: %d entries, error %v, want 4 entries", count, err)
+	}
+}
+
+func TestVerifyAuditLogDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines [][]byte) [][]byte
+		count  int    // Valid entries before the broken one
+		err    string // Expected in the error
+	}{
+		{
+			name: "edited",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte("admin_addPeer"), []byte("admin_addTrust"), 1)
+				return lines
+			},
+			count: 1,
+			err:   "line 2: entry hash mismatch",
+		},
+		{
+			name: "reordered",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			count: 1,
+			err:   "line 2: sequence 2, expected 1",
+		},
+		{
+			name: "removed",
+			tamper: func(lines [][]byte) [][]byte {
+				return append(lines[:1], lines[2:]...)
+			},
+			count: 1,
+			err:   "line 2: sequence 2, expected 1",
+		},
+		{
+			name: "malformed",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[2] = []byte("{\n")
+				return lines
+			},
+			count: 2,
+			err:   "line 3: malformed entry",
+		},
+	}
+	for _, test := range tests {
+		path := writeAuditLog(t, 4)
+		writeAuditLines(t, path, test.tamper(readAuditLines(t, path)))
+
+		count, err := VerifyAuditLog(path)
+		if err == nil || !strings.Contains(err.Error(), test.err) || count != test.count {
+			t.Errorf("%s: %d entries, error %v, want %d entries and %q", test.name, count, err, test.count, test.err)
+		}
+	}
+}
+
+func TestOpenAuditLogDropsTornEntry(t *testing.T) {
+	path := writeAuditLog(t, 3)
+	lines := readAuditLines(t, path)
+	torn := lines[2][:len(lines[2])/2]
+	writeAuditLines(t, path, append(lines[:2], torn))
+
+	log, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append(TransportIPC, "admin_addPeer", nil, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	// The torn entry is gone and the new one continues the chain after the
+	// second entry
+	if count, err := VerifyAuditLog(path); err != nil || count != 3 {
+		t.Errorf("verify: %d entries, error %v, want 3 entries", count, err)
+	}
+	if lines := readAuditLines(t, path); len(lines) != 3 {
+		t.Errorf("%d lines in the log, want 3", len(lines))
+	}
+}
+
+func TestOpenAuditLogRejectsModifiedLastEntry(t *testing.T) {
+	path := writeAuditLog(t, 2)
+	lines := readAuditLines(t, path)
+	lines[1] = bytes.Replace(lines[1], []byte("admin_addPeer"), []byte("admin_addTrust"), 1)
+	writeAuditLines(t, path, lines)
+
+	if _, err := OpenAuditLog(path); err == nil {
+		t.Error("opened an audit log whose last entry was modified")
+	}
+}
@@ -23,7 +23,9 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
+	"github.com/klaytn/guardian/flags"
 	"github.com/klaytn/klaytn/cmd/utils"
 	"github.com/klaytn/klaytn/crypto"
 	"github.com/klaytn/klaytn/log"
@@ -53,6 +55,26 @@ type GuardianConfig struct {
 	netrestrict  string
 	writeAddress bool
 
+	nodeKeystore             string
+	nodeKeystorePasswordFile string
+	rotationPort             int
+
+	allowedPeerTypes string
+	maxCNPeers       int
+	maxPNPeers       int
+	maxENPeers       int
+	reservedCNPeers  int
+	peerTypePolicy   *PeerTypePolicy
+
+	peerHistoryRetention  time.Duration
+	peerHistoryMaxEntries int
+
+	alerts AlertConfig
+
+	// Network preset
+	presets      []*NetworkPreset
+	networkIDSet bool
+
 	// Context
 	restrictList *netutil.Netlist
 	nodeKey      *ecdsa.PrivateKey
@@ -94,6 +116,29 @@ func NewGuardianConfig(ctx *cli.Context) *GuardianConfig {
 		netrestrict:  ctx.String(utils.NetrestrictFlag.Name),
 		writeAddress: ctx.Bool(utils.WriteAddressFlag.Name),
 
+		nodeKeystore:             ctx.String(flags.NodeKeystoreFlag.Name),
+		nodeKeystorePasswordFile: ctx.String(flags.NodeKeystorePasswordFileFlag.Name),
+		rotationPort:             ctx.Int(flags.RotationPortFlag.Name),
+
+		allowedPeerTypes: ctx.String(flags.AllowedPeerTypesFlag.Name),
+		maxCNPeers:       ctx.Int(flags.MaxCNPeersFlag.Name),
+		maxPNPeers:       ctx.Int(flags.MaxPNPeersFlag.Name),
+		maxENPeers:       ctx.Int(flags.MaxENPeersFlag.Name),
+		reservedCNPeers:  ctx.Int(flags.ReservedCNPeersFlag.Name),
+
+		peerHistoryRetention:  ctx.Duration(flags.PeerHistoryRetentionFlag.Name),
+		peerHistoryMaxEntries: ctx.Int(flags.PeerHistoryMaxEntriesFlag.Name),
+
+		alerts: AlertConfig{
+			Webhook:        ctx.String(flags.AlertWebhookFlag.Name),
+			Format:         ctx.String(flags.AlertFormatFlag.Name),
+			RoutingKey:     ctx.String(flags.AlertRoutingKeyFlag.Name),
+			Rules:          ctx.StringSlice(flags.AlertRulesFlag.Name),
+			MinPublicPeers: ctx.Int(flags.AlertMinPublicPeersFlag.Name),
+			Retries:        ctx.Int(flags.AlertRetriesFlag.Name),
+			RetryBackoff:   ctx.Duration(flags.AlertRetryBackoffFlag.Name),
+		},
+
 		IPCPath: "klay.ipc",
 		DataDir: ctx.String(utils.DataDirFlag.Name),
 
@@ -143,16 +188,19 @@ func SetIPC(ctx *cli.Context, cfg *GuardianConfig) {
 
 func SetP2PConfig(ctx *cli.Context, cfg *GuardianConfig) {
 	utils.SetP2PConfig(ctx, &cfg.serverConfig)
+	// Message events feed the per-peer traffic counters of admin_peers
+	cfg.serverConfig.EnableMsgEvents = true
 }
 
 func (cfg *GuardianConfig) CheckCMDState() int {
 	if cfg.genKeyPath != "" {
 		return GenerateNodeKeySpecified
 	}
-	if cfg.nodeKeyFile == "" && cfg.nodeKeyHex == "" {
+	sources := cfg.nodeKeySources()
+	if sources == 0 {
 		return NoPrivateKeyPathSpecified
 	}
-	if cfg.nodeKeyFile != "" && cfg.nodeKeyHex != "" {
+	if sources > 1 {
 		return NodeKeyDuplicated
 	}
 	if cfg.writeAddress {
@@ -161,40 +209,127 @@ func (cfg *GuardianConfig) CheckCMDState() int {
 	return GoodToGo
 }
 
-func (cfg *GuardianConfig) GenerateNodeKey() {
+// nodeKeySources returns how many of the mutually exclusive node key options
+// are given.
+func (cfg *GuardianConfig) nodeKeySources() int {
+	n := 0
+	for _, source := range []string{cfg.nodeKeyFile, cfg.nodeKeyHex, cfg.nodeKeystore} {
+		if source != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// GenerateNodeKey generates a node key and saves it to the --genkey path.
+func (cfg *GuardianConfig) GenerateNodeKey() error {
+	_, err := newNodeKey(cfg.genKeyPath)
+	return err
+}
+
+// newNodeKey generates a new node key and saves it to path unless path is empty.
+func newNodeKey(path string) (*ecdsa.PrivateKey, error) {
 	nodeKey, err := crypto.GenerateKey()
 	if err != nil {
-		log.Fatalf("could not generate key: %v", err)
+		return nil, fmt.Errorf("could not generate key: %v", err)
 	}
-	if err = crypto.SaveECDSA(cfg.genKeyPath, nodeKey); err != nil {
-		log.Fatalf("%v", err)
+	if path != "" {
+		if err = crypto.SaveECDSA(path, nodeKey); err != nil {
+			return nil, err
+		}
 	}
-	os.Exit(0)
+	return nodeKey, nil
 }
 
-func (cfg *GuardianConfig) DoWriteOutAddress() {
-	err := cfg.ReadNodeKey()
+// persistNodeKey replaces the configured node key file with key, so that the
+// key survives a restart, and returns the path written and the path of the
+// backup of the old key. A key given by --nodekeyhex can not be rewritten.
+func (cfg *GuardianConfig) persistNodeKey(key *ecdsa.PrivateKey) (string, string, error) {
+	var path string
+	switch {
+	case cfg.nodeKeyFile != "":
+		path = cfg.nodeKeyFile
+	case cfg.nodeKeystore != "":
+		path = cfg.nodeKeystore
+	default:
+		return "", "", ErrRotationHexKey
+	}
+	backup := path + ".bak"
+	if err := copyFile(path, backup); err != nil {
+		return "", "", fmt.Errorf("could not back up the node key: %v", err)
+	}
+	if cfg.nodeKeyFile != "" {
+		return path, backup, crypto.SaveECDSA(path, key)
+	}
+	passphrase, err := ReadNodeKeyPassphrase(cfg.nodeKeystorePasswordFile)
 	if err != nil {
-		log.Fatalf("Failed to read node key: %v", err)
+		return "", "", err
 	}
-	fmt.Printf("%v\n", discover.PubkeyID(&(cfg.nodeKey).PublicKey))
-	os.Exit(0)
+	return path, backup, SaveNodeKeystore(path, key, passphrase)
+}
+
+// copyFile copies the file src to dst, readable by the owner only.
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0o600)
+}
+
+// DoWriteOutAddress prints the node ID of the configured node key.
+func (cfg *GuardianConfig) DoWriteOutAddress() error {
+	if err := cfg.ReadNodeKey(); err != nil {
+		return fmt.Errorf("Failed to read node key: %v", err)
+	}
+	fmt.Printf("%v\n", discover.PubkeyID(&cfg.nodeKey.PublicKey))
+	return nil
 }
 
 func (cfg *GuardianConfig) ReadNodeKey() error {
 	var err error
-	if cfg.nodeKeyFile != "" {
+	switch {
+	case cfg.nodeKeyFile != "":
 		cfg.nodeKey, err = crypto.LoadECDSA(cfg.nodeKeyFile)
-		return err
-	}
-	if cfg.nodeKeyHex != "" {
+	case cfg.nodeKeyHex != "":
 		cfg.nodeKey, err = crypto.HexToECDSA(cfg.nodeKeyHex)
+	case cfg.nodeKeystore != "":
+		var passphrase string
+		if passphrase, err = ReadNodeKeyPassphrase(cfg.nodeKeystorePasswordFile); err != nil {
+			return err
+		}
+		cfg.nodeKey, err = LoadNodeKeystore(cfg.nodeKeystore, passphrase)
+	default:
+		return ErrNoNodeKey
+	}
+	if err != nil {
 		return err
 	}
+	// utils.SetP2PConfig only knows the plaintext key options, so hand the
+	// resolved key to the server explicitly.
+	cfg.serverConfig.PrivateKey = cfg.nodeKey
 	return nil
 }
 
+// NodeKey returns the node key loaded by ReadNodeKey.
+func (cfg *GuardianConfig) NodeKey() *ecdsa.PrivateKey {
+	return cfg.nodeKey
+}
+
 func (cfg *GuardianConfig) ValidateNetworkParameter() error {
+	if err := cfg.validatePreset(); err != nil {
+		return err
+	}
+	if err := cfg.validateMultiChannel(); err != nil {
+		return err
+	}
+	if err := cfg.validateRotationPorts(); err != nil {
+		return err
+	}
+	if err := cfg.setPeerTypePolicy(); err != nil {
+		return err
+	}
+
 	var err error
 	if cfg.natFlag != "" {
 		cfg.natm, err = nat.Parse(cfg.natFlag)
@@ -219,6 +354,15 @@ func (cfg *GuardianConfig) ValidateNetworkParameter() error {
 	return nil
 }
 
+// datadirFile returns the path of a file in the data directory, or an empty
+// string if there is no data directory.
+func (c *GuardianConfig) datadirFile(name string) string {
+	if c.DataDir == "" {
+		return ""
+	}
+	return filepath.Join(c.DataDir, name)
+}
+
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
 // account the set data folders as well as the designated platform we're currently
 // running on.
@@ -244,6 +388,68 @@ func (c *GuardianConfig) IPCEndpoint() string {
 	return c.IPCPath
 }
 
+// Redacted replaces secrets in the output of Items.
+const Redacted = "<redacted>"
+
+// ConfigItem is a resolved configuration value together with the name of the
+// flag it is derived from.
+type ConfigItem struct {
+	Flag  string
+	Value interface{}
+}
+
+// Items returns the resolved configuration values in a stable order, with
+// secrets redacted.
+func (cfg *GuardianConfig) Items() []ConfigItem {
+	redact := func(secret string) string {
+		if secret == "" {
+			return ""
+		}
+		return Redacted
+	}
+	preset := func(p *NetworkPreset) bool {
+		return cfg.Preset() == p
+	}
+	return []ConfigItem{
+		{utils.CypressFlag.Name, preset(CypressPreset)},
+		{utils.BaobabFlag.Name, preset(BaobabPreset)},
+		{flags.DevnetFlag.Name, preset(DevnetPreset)},
+		{utils.NetworkIdFlag.Name, cfg.networkID},
+		{utils.DataDirFlag.Name, cfg.DataDir},
+		{utils.IPCPathFlag.Name, cfg.IPCEndpoint()},
+		{utils.BNAddrFlag.Name, cfg.addr},
+		{utils.NATFlag.Name, cfg.natFlag},
+		{utils.NetrestrictFlag.Name, cfg.netrestrict},
+		{utils.AuthorizedNodesFlag.Name, nodeURLs(cfg.AuthorizedNodes)},
+		{utils.NodeKeyFileFlag.Name, cfg.nodeKeyFile},
+		{utils.NodeKeyHexFlag.Name, redact(cfg.nodeKeyHex)},
+		{flags.NodeKeystoreFlag.Name, cfg.nodeKeystore},
+		{flags.NodeKeystorePasswordFileFlag.Name, cfg.nodeKeystorePasswordFile},
+		{flags.RotationPortFlag.Name, cfg.rotationPort},
+		{flags.AllowedPeerTypesFlag.Name, cfg.allowedPeerTypes},
+		{flags.MaxCNPeersFlag.Name, cfg.maxCNPeers},
+		{flags.MaxPNPeersFlag.Name, cfg.maxPNPeers},
+		{flags.MaxENPeersFlag.Name, cfg.maxENPeers},
+		{flags.ReservedCNPeersFlag.Name, cfg.reservedCNPeers},
+		{flags.PeerHistoryRetentionFlag.Name, cfg.peerHistoryRetention.String()},
+		{flags.PeerHistoryMaxEntriesFlag.Name, cfg.peerHistoryMaxEntries},
+		{flags.AlertWebhookFlag.Name, redact(cfg.alerts.Webhook)},
+		{flags.AlertFormatFlag.Name, cfg.alerts.Format},
+		{flags.AlertRoutingKeyFlag.Name, redact(cfg.alerts.RoutingKey)},
+		{flags.AlertRulesFlag.Name, cfg.alerts.Rules},
+		{flags.AlertMinPublicPeersFlag.Name, cfg.alerts.MinPublicPeers},
+		{flags.AlertRetriesFlag.Name, cfg.alerts.Retries},
+		{flags.AlertRetryBackoffFlag.Name, cfg.alerts.RetryBackoff.String()},
+		{utils.ListenPortFlag.Name, cfg.serverConfig.ListenAddr},
+		{utils.SubListenPortFlag.Name, cfg.serverConfig.SubListenAddr},
+		{utils.MultiChannelUseFlag.Name, cfg.serverConfig.EnableMultiChannelServer},
+		{utils.MaxConnectionsFlag.Name, cfg.serverConfig.MaxPhysicalConnections},
+		{utils.MaxPendingPeersFlag.Name, cfg.serverConfig.MaxPendingPeers},
+		{utils.NoDiscoverFlag.Name, cfg.serverConfig.NoDiscovery},
+		{utils.BootnodesFlag.Name, nodeURLs(cfg.serverConfig.BootstrapNodes)},
+	}
+}
+
 func DefaultIPCEndpoint(clientIdentifier string) string {
 	if clientIdentifier == "" {
 		clientIdentifier = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
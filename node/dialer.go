@@ -0,0 +1,176 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/klaytn/klaytn/networks/p2p"
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+// dialTimeout bounds the TCP connect of an outbound peer connection, as the
+// default dialer of the p2p server does.
+const dialTimeout = 15 * time.Second
+
+// dialOutcome is what a watcher of dialTracker learns about a connection
+// attempt the p2p server made.
+type dialOutcome struct {
+	Stage string        // "dial" or "handshake"
+	Err   error         // nil if the TCP connect succeeded
+	RTT   time.Duration // Duration of the TCP connect, one network round trip
+}
+
+// dialTracker is the dialer of the p2p servers. The servers report neither
+// failed dials nor failed handshakes, so the dialer passes the outcome of its
+// dials, and the closing of connections that never became peers, to the
+// watchers of the dialed node.
+type dialTracker struct {
+	dialer p2p.TCPDialer
+
+	lock     sync.Mutex
+	watchers map[discover.NodeID]map[chan *dialOutcome]struct{}
+}
+
+func newDialTracker() *dialTracker {
+	return &dialTracker{
+		dialer:   p2p.TCPDialer{Dialer: &net.Dialer{Timeout: dialTimeout}},
+		watchers: make(map[discover.NodeID]map[chan *dialOutcome]struct{}),
+	}
+}
+
+// watch returns a channel receiving the outcomes of the connection attempts
+// to a node until unwatch is called.
+func (d *dialTracker) watch(id discover.NodeID) chan *dialOutcome {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	ch := make(chan *dialOutcome, 4)
+	if d.watchers[id] == nil {
+		d.watchers[id] = make(map[chan *dialOutcome]struct{})
+	}
+	d.watchers[id][ch] = struct{}{}
+	return ch
+}
+
+func (d *dialTracker) unwatch(id discover.NodeID, ch chan *dialOutcome) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.watchers[id], ch)
+	if len(d.watchers[id]) == 0 {
+		delete(d.watchers, id)
+	}
+}
+
+func (d *dialTracker) notify(id discover.NodeID, outcome *dialOutcome) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for ch := range d.watchers[id] {
+		select {
+		case ch <- outcome:
+		default:
+		}
+	}
+}
+
+// Dial implements p2p.NodeDialer.
+func (d *dialTracker) Dial(dest *discover.Node) (net.Conn, error) {
+	start := time.Now()
+	conn, err := d.dialer.Dial(dest)
+	d.notify(dest.ID, &dialOutcome{Stage: "dial", Err: err, RTT: time.Since(start)})
+	if err != nil {
+		return nil, err
+	}
+	return d.wrap(dest.ID, conn), nil
+}
+
+// DialMulti implements p2p.NodeDialer for multi-channel peers.
+func (d *dialTracker) DialMulti(dest *discover.Node) ([]net.Conn, error) {
+	start := time.Now()
+	conns, err := d.dialer.DialMulti(dest)
+	d.notify(dest.ID, &dialOutcome{Stage: "dial", Err: err, RTT: time.Since(start)})
+	if err != nil {
+		return nil, err
+	}
+	for i, conn := range conns {
+		conns[i] = d.wrap(dest.ID, conn)
+	}
+	return conns, nil
+}
+
+func (d *dialTracker) wrap(id discover.NodeID, conn net.Conn) net.Conn {
+	return &watchedConn{Conn: conn, id: id, tracker: d}
+}
+
+// watchedConn reports its closing, with the first I/O error if there was one,
+// to the watchers of the remote node. A watcher still waiting for the node to
+// be added takes this as a failed handshake.
+type watchedConn struct {
+	net.Conn
+	id      discover.NodeID
+	tracker *dialTracker
+
+	lock   sync.Mutex
+	ioErr  error
+	closed bool
+}
+
+func (c *watchedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.setErr(err)
+	return n, err
+}
+
+func (c *watchedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.setErr(err)
+	return n, err
+}
+
+func (c *watchedConn) setErr(err error) {
+	if err == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.ioErr == nil && !c.closed {
+		c.ioErr = err
+	}
+}
+
+func (c *watchedConn) Close() error {
+	c.lock.Lock()
+	first, ioErr := !c.closed, c.ioErr
+	c.closed = true
+	c.lock.Unlock()
+
+	if first {
+		err := errors.New("connection closed")
+		if ioErr != nil {
+			err = fmt.Errorf("connection lost: %v", ioErr)
+		}
+		c.tracker.notify(c.id, &dialOutcome{Stage: "handshake", Err: err})
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"net"
+	"testing"
+
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+func TestWatchedConnReportsClose(t *testing.T) {
+	tests := []struct {
+		remoteClose bool
+		want        string
+	}{
+		{false, "connection closed"},
+		{true, "connection lost: EOF"},
+	}
+	for _, test := range tests {
+		d := newDialTracker()
+		id, other := discover.NodeID{1}, discover.NodeID{2}
+		watched, unrelated := d.watch(id), d.watch(other)
+
+		local, remote := net.Pipe()
+		conn := d.wrap(id, local)
+		if test.remoteClose {
+			remote.Close()
+			conn.Read(make([]byte, 1))
+		}
+		conn.Close()
+		conn.Close() // Reported once
+
+		select {
+		case outcome := <-watched:
+			if outcome.Stage != "handshake" || outcome.Err == nil || outcome.Err.Error() != test.want {
+				t.Errorf("remote close %v: got %s %v, want handshake %q", test.remoteClose, outcome.Stage, outcome.Err, test.want)
+			}
+		default:
+			t.Errorf("remote close %v: close not reported", test.remoteClose)
+		}
+		if len(watched) != 0 || len(unrelated) != 0 {
+			t.Errorf("remote close %v: unexpected outcomes, %d for the node, %d for another", test.remoteClose, len(watched), len(unrelated))
+		}
+		d.unwatch(id, watched)
+		d.unwatch(other, unrelated)
+		if len(d.watchers) != 0 {
+			t.Errorf("watchers left after unwatch: %d", len(d.watchers))
+		}
+		remote.Close()
+	}
+}
@@ -2,6 +2,7 @@ package node
 
 import (
 	"errors"
+	"fmt"
 	"syscall"
 )
 
@@ -10,6 +11,10 @@ var (
 	ErrNodeStopped    = errors.New("node not started")
 	ErrNodeRunning    = errors.New("node already running")
 	ErrServiceUnknown = errors.New("unknown service")
+	ErrZeroTimeout    = errors.New("timeout must be at least one second")
+
+	ErrNoNodeKey         = errors.New("Use --nodekey, --nodekeyhex or --nodekeystore to specify a private key")
+	ErrNodeKeyDuplicated = errors.New("Options --nodekey, --nodekeyhex and --nodekeystore are mutually exclusive")
 
 	datadirInUseErrnos = map[uint]bool{11: true, 32: true, 35: true}
 )
@@ -20,3 +25,16 @@ func convertFileLockError(err error) error {
 	}
 	return err
 }
+
+// PeerConnectError tells why a peer added with admin_addPeerAndWait did not
+// complete the handshake.
+type PeerConnectError struct {
+	Peer    string // kni of the peer
+	Stage   string // "dial" or "handshake"
+	Reason  string // Why the stage failed
+	Timeout bool   // Whether the stage did not complete in time
+}
+
+func (e *PeerConnectError) Error() string {
+	return fmt.Sprintf("peer %s not connected: %s failed: %s", e.Peer, e.Stage, e.Reason)
+}
@@ -0,0 +1,267 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/klaytn/klaytn/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+// NodeKeyPassphraseEnv is the environment variable consulted for the keystore
+// passphrase when no password file is configured. Unlike the GUARDIAN_* flag
+// variables it holds the secret itself.
+const NodeKeyPassphraseEnv = "GUARDIAN_NODEKEY_PASSPHRASE"
+
+const (
+	keystoreVersion = 3
+
+	// Standard scrypt parameters of the Web3 Secret Storage definition.
+	keystoreScryptN     = 1 << 18
+	keystoreScryptP     = 1
+	keystoreScryptR     = 8
+	keystoreScryptDKLen = 32
+
+	// Bounds of the scrypt parameters accepted from a keystore file, so that a
+	// malformed file can not make the decryption exhaust memory or CPU. The
+	// memory used is 128*n*r bytes, 256 MiB with the standard parameters.
+	keystoreMaxScryptMem   = 128 * keystoreScryptN * keystoreScryptR
+	keystoreMaxScryptP     = 16
+	keystoreMaxScryptDKLen = 64
+)
+
+var (
+	ErrKeystoreDecrypt    = errors.New("could not decrypt node key with given passphrase")
+	ErrNoKeystorePassword = errors.New("no keystore passphrase: use --nodekeystore.passwordfile or " + NodeKeyPassphraseEnv)
+)
+
+// encryptedNodeKey is the Web3 Secret Storage (version 3) representation of a
+// node key, so that the file can also be handled by the usual keystore tools.
+type encryptedNodeKey struct {
+	Address string        `json:"address"`
+	Crypto  keystoreCrypt `json:"crypto"`
+	ID      string        `json:"id"`
+	Version int           `json:"version"`
+}
+
+type keystoreCrypt struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams keystoreCipherParams   `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type keystoreCipherParams struct {
+	IV string `json:"iv"`
+}
+
+// EncryptNodeKey encrypts the node key with the passphrase and returns the
+// keystore JSON.
+func EncryptNodeKey(key *ecdsa.PrivateKey, passphrase string) ([]byte, error) {
+	return encryptNodeKey(key, passphrase, keystoreScryptN, keystoreScryptP)
+}
+
+// encryptNodeKey encrypts the node key using the given scrypt parameters.
+func encryptNodeKey(key *ecdsa.PrivateKey, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, scryptN, keystoreScryptR, scryptP, keystoreScryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	cipherText, err := aesCTRXOR(derivedKey[:16], crypto.FromECDSA(key), iv)
+	if err != nil {
+		return nil, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	id[6] = (id[6] & 0x0f) | 0x40 // version 4 (random) UUID
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	return json.Marshal(&encryptedNodeKey{
+		Address: hex.EncodeToString(crypto.PubkeyToAddress(key.PublicKey).Bytes()),
+		Crypto: keystoreCrypt{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: keystoreCipherParams{IV: hex.EncodeToString(iv)},
+			KDF:          "scrypt",
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     keystoreScryptR,
+				"p":     scryptP,
+				"dklen": keystoreScryptDKLen,
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(mac),
+		},
+		ID:      fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		Version: keystoreVersion,
+	})
+}
+
+// DecryptNodeKey decrypts a keystore JSON produced by EncryptNodeKey (or any
+// other scrypt based version 3 keystore) and returns the node key.
+func DecryptNodeKey(keyjson []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	var k encryptedNodeKey
+	if err := json.Unmarshal(keyjson, &k); err != nil {
+		return nil, err
+	}
+	if k.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version: %d", k.Version)
+	}
+	if k.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported keystore cipher: %s", k.Crypto.Cipher)
+	}
+	if k.Crypto.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported keystore kdf: %s", k.Crypto.KDF)
+	}
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid keystore iv length: %d", len(iv))
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(kdfString(k.Crypto.KDFParams, "salt"))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		n     = kdfInt(k.Crypto.KDFParams, "n")
+		r     = kdfInt(k.Crypto.KDFParams, "r")
+		p     = kdfInt(k.Crypto.KDFParams, "p")
+		dkLen = kdfInt(k.Crypto.KDFParams, "dklen")
+	)
+	if err := checkScryptParams(n, r, p, dkLen); err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, n, r, p, dkLen)
+	if err != nil {
+		return nil, err
+	}
+	calculatedMAC := crypto.Keccak256(derivedKey[16:32], cipherText)
+	if subtle.ConstantTimeCompare(calculatedMAC, mac) != 1 {
+		return nil, ErrKeystoreDecrypt
+	}
+	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(plainText)
+}
+
+// LoadNodeKeystore reads and decrypts the keystore file at path.
+func LoadNodeKeystore(path, passphrase string) (*ecdsa.PrivateKey, error) {
+	keyjson, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptNodeKey(keyjson, passphrase)
+}
+
+// SaveNodeKeystore encrypts the node key and writes it to path with
+// restrictive permissions.
+func SaveNodeKeystore(path string, key *ecdsa.PrivateKey, passphrase string) error {
+	keyjson, err := EncryptNodeKey(key, passphrase)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, keyjson, 0o600)
+}
+
+// ReadNodeKeyPassphrase returns the keystore passphrase from the password file
+// if one is given, falling back to the NodeKeyPassphraseEnv variable.
+func ReadNodeKeyPassphrase(passwordFile string) (string, error) {
+	if passwordFile != "" {
+		text, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %v", err)
+		}
+		// Only the first line is used, like the klaytn --password handling.
+		return strings.TrimRight(strings.SplitN(string(text), "\n", 2)[0], "\r"), nil
+	}
+	if passphrase, ok := os.LookupEnv(NodeKeyPassphraseEnv); ok {
+		return passphrase, nil
+	}
+	return "", ErrNoKeystorePassword
+}
+
+func aesCTRXOR(key, inText, iv []byte) ([]byte, error) {
+	aesBlock, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	stream := cipher.NewCTR(aesBlock, iv)
+	outText := make([]byte, len(inText))
+	stream.XORKeyStream(outText, inText)
+	return outText, nil
+}
+
+// checkScryptParams rejects scrypt parameters outside of the accepted bounds.
+func checkScryptParams(n, r, p, dkLen int) error {
+	if n <= 1 || n&(n-1) != 0 || r <= 0 || n > keystoreMaxScryptMem/128/r {
+		return fmt.Errorf("keystore scrypt parameters n=%d r=%d out of range", n, r)
+	}
+	if p <= 0 || p > keystoreMaxScryptP {
+		return fmt.Errorf("keystore scrypt parameter p=%d out of range [1, %d]", p, keystoreMaxScryptP)
+	}
+	if dkLen < 32 || dkLen > keystoreMaxScryptDKLen {
+		return fmt.Errorf("keystore dklen %d out of range [32, %d]", dkLen, keystoreMaxScryptDKLen)
+	}
+	return nil
+}
+
+func kdfString(params map[string]interface{}, name string) string {
+	s, _ := params[name].(string)
+	return s
+}
+
+func kdfInt(params map[string]interface{}, name string) int {
+	f, _ := params[name].(float64)
+	return int(f)
+}
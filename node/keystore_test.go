@@ -0,0 +1,100 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/klaytn/klaytn/crypto"
+)
+
+// Light scrypt parameters, so that the tests stay fast.
+const (
+	testScryptN = 1 << 4
+	testScryptP = 1
+)
+
+func TestNodeKeystoreRoundTrip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := encryptNodeKey(key, "secret", testScryptN, testScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := DecryptNodeKey(keyjson, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(crypto.FromECDSA(decrypted), crypto.FromECDSA(key)) {
+		t.Error("decrypted key differs from the encrypted one")
+	}
+	if _, err := DecryptNodeKey(keyjson, "wrong"); err != ErrKeystoreDecrypt {
+		t.Errorf("wrong passphrase: error %v, want %v", err, ErrKeystoreDecrypt)
+	}
+}
+
+func TestDecryptNodeKeyRejectsMalformed(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := encryptNodeKey(key, "secret", testScryptN, testScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(k *encryptedNodeKey)
+	}{
+		{"version", func(k *encryptedNodeKey) { k.Version = 1 }},
+		{"cipher", func(k *encryptedNodeKey) { k.Crypto.Cipher = "aes-128-cbc" }},
+		{"kdf", func(k *encryptedNodeKey) { k.Crypto.KDF = "pbkdf2" }},
+		{"truncated iv", func(k *encryptedNodeKey) { k.Crypto.CipherParams.IV = k.Crypto.CipherParams.IV[:8] }},
+		{"empty iv", func(k *encryptedNodeKey) { k.Crypto.CipherParams.IV = "" }},
+		{"non-hex iv", func(k *encryptedNodeKey) { k.Crypto.CipherParams.IV = "zz" }},
+		{"truncated ciphertext", func(k *encryptedNodeKey) { k.Crypto.CipherText = k.Crypto.CipherText[:16] }},
+		{"missing n", func(k *encryptedNodeKey) { delete(k.Crypto.KDFParams, "n") }},
+		{"n not a power of 2", func(k *encryptedNodeKey) { k.Crypto.KDFParams["n"] = 1000 }},
+		{"oversized n", func(k *encryptedNodeKey) { k.Crypto.KDFParams["n"] = 1 << 30 }},
+		{"oversized r", func(k *encryptedNodeKey) { k.Crypto.KDFParams["r"] = 1 << 20 }},
+		{"zero r", func(k *encryptedNodeKey) { k.Crypto.KDFParams["r"] = 0 }},
+		{"oversized p", func(k *encryptedNodeKey) { k.Crypto.KDFParams["p"] = 1 << 10 }},
+		{"zero p", func(k *encryptedNodeKey) { k.Crypto.KDFParams["p"] = 0 }},
+		{"short dklen", func(k *encryptedNodeKey) { k.Crypto.KDFParams["dklen"] = 16 }},
+		{"oversized dklen", func(k *encryptedNodeKey) { k.Crypto.KDFParams["dklen"] = 1 << 20 }},
+		{"modified mac", func(k *encryptedNodeKey) { k.Crypto.MAC = "00" + k.Crypto.MAC[2:] }},
+	}
+	for _, test := range tests {
+		var k encryptedNodeKey
+		if err := json.Unmarshal(keyjson, &k); err != nil {
+			t.Fatal(err)
+		}
+		test.modify(&k)
+		modified, err := json.Marshal(&k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecryptNodeKey(modified, "secret"); err == nil {
+			t.Errorf("%s: decrypted a malformed keystore", test.name)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klaytn/guardian/flags"
+	"github.com/klaytn/klaytn/log"
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+var ErrLoggingNotSetup = errors.New("logging is not set up")
+
+var (
+	glogger     *log.GlogHandler // Root log handler, nil until SetupLogging
+	glogLock    sync.Mutex
+	logRotation *lumberjack.Logger
+)
+
+// SetupLogging installs the root log handler according to the log flags:
+// output format, global and per-module verbosity, and an optional log file
+// rotated by size whose old backups are removed by age and count.
+func SetupLogging(ctx *cli.Context) error {
+	var (
+		output   io.Writer = colorable.NewColorableStderr()
+		useColor           = (isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd())) && os.Getenv("TERM") != "dumb"
+	)
+	if file := ctx.String(flags.LogFileFlag.Name); file != "" {
+		logRotation = &lumberjack.Logger{
+			Filename:   file,
+			MaxSize:    ctx.Int(flags.LogFileMaxSizeFlag.Name),
+			MaxAge:     ctx.Int(flags.LogFileMaxAgeFlag.Name),
+			MaxBackups: ctx.Int(flags.LogFileMaxBackupsFlag.Name),
+		}
+		output, useColor = logRotation, false
+	}
+
+	var format log.Format
+	switch f := ctx.String(flags.LogFormatFlag.Name); f {
+	case "", "terminal":
+		format = log.TerminalFormat(useColor)
+	case "json":
+		format = log.JSONFormat()
+	case "logfmt":
+		format = log.LogfmtFormat()
+	default:
+		return fmt.Errorf("unknown log format %q, expected terminal, json or logfmt", f)
+	}
+
+	handler := log.NewGlogHandler(log.StreamHandler(output, format))
+	handler.Verbosity(log.Lvl(ctx.Int(flags.VerbosityFlag.Name)))
+	if err := handler.Vmodule(ctx.String(flags.VmoduleFlag.Name)); err != nil {
+		return err
+	}
+	log.Root().SetHandler(handler)
+
+	glogLock.Lock()
+	glogger = handler
+	glogLock.Unlock()
+
+	return SetModuleVerbosities(ctx.String(flags.ModuleVerbosityFlag.Name))
+}
+
+// CloseLogging closes the log file, if any.
+func CloseLogging() error {
+	if logRotation == nil {
+		return nil
+	}
+	return logRotation.Close()
+}
+
+// SetVerbosity changes the global log verbosity at runtime.
+func SetVerbosity(level int) error {
+	glogLock.Lock()
+	defer glogLock.Unlock()
+
+	if glogger == nil {
+		return ErrLoggingNotSetup
+	}
+	if level < int(log.LvlCrit) || level > int(log.LvlTrace) {
+		return fmt.Errorf("verbosity %d out of range [%d, %d]", level, log.LvlCrit, log.LvlTrace)
+	}
+	glogger.Verbosity(log.Lvl(level))
+	return nil
+}
+
+// SetVmodule changes the per-file glog verbosity pattern at runtime.
+func SetVmodule(pattern string) error {
+	glogLock.Lock()
+	defer glogLock.Unlock()
+
+	if glogger == nil {
+		return ErrLoggingNotSetup
+	}
+	return glogger.Vmodule(pattern)
+}
+
+// SetModuleVerbosities changes the verbosity of klaytn log modules given as a
+// comma separated list of module=level pairs, e.g. "node=4,networks/p2p=2".
+func SetModuleVerbosities(spec string) error {
+	if strings.TrimSpace(spec) == "" {
+		return nil
+	}
+	for _, pair := range SplitAndTrim(spec) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid module verbosity %q, expected module=level", pair)
+		}
+		level, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return fmt.Errorf("invalid level in module verbosity %q: %v", pair, err)
+		}
+		if err := log.ChangeLogLevelWithName(strings.TrimSpace(kv[0]), log.Lvl(level)); err != nil {
+			return fmt.Errorf("failed to set verbosity of module %s: %v", kv[0], err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/klaytn/guardian/flags"
+	"github.com/klaytn/klaytn/cmd/utils"
+)
+
+// validateMultiChannel checks that the multi-channel server has a sub port of
+// its own and warns about authorized nodes whose link cannot use both
+// channels. A multi-channel peer connects on the main and the sub port; the
+// klay protocol then keeps consensus messages on the main channel and the
+// block and transaction traffic on the sub channel.
+func (cfg *GuardianConfig) validateMultiChannel() error {
+	serverConfig := &cfg.serverConfig
+	if !serverConfig.EnableMultiChannelServer {
+		for _, node := range cfg.AuthorizedNodes {
+			if len(node.TCPs) > 1 {
+				cfg.Logger.Warn("Authorized node has a sub port, but --multichannel is off", "kni", node)
+			}
+		}
+		return nil
+	}
+
+	if len(serverConfig.SubListenAddr) == 0 {
+		return fmt.Errorf("--%s requires --%s", utils.MultiChannelUseFlag.Name, utils.SubListenPortFlag.Name)
+	}
+	_, port, err := net.SplitHostPort(serverConfig.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %v", serverConfig.ListenAddr, err)
+	}
+	for _, addr := range serverConfig.SubListenAddr {
+		_, subPort, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("invalid sub listen address %q: %v", addr, err)
+		}
+		if subPort == port {
+			return fmt.Errorf("--%s and --%s must differ, both are %s",
+				utils.ListenPortFlag.Name, utils.SubListenPortFlag.Name, port)
+		}
+	}
+	for _, node := range cfg.AuthorizedNodes {
+		if len(node.TCPs) < 2 {
+			cfg.Logger.Warn("Authorized node has no sub port, its link uses a single channel", "kni", node)
+		}
+	}
+	return nil
+}
+
+// rotationSubListenAddr returns the sub listen addresses of the new identity
+// during a key rotation: the ports after --rotationport.
+func (cfg *GuardianConfig) rotationSubListenAddr() []string {
+	if !cfg.serverConfig.EnableMultiChannelServer {
+		return nil
+	}
+	addrs := make([]string, len(cfg.serverConfig.SubListenAddr))
+	for i := range addrs {
+		addrs[i] = fmt.Sprintf(":%d", cfg.rotationPort+1+i)
+	}
+	return addrs
+}
+
+// validateRotationPorts checks that the listeners of the new identity during
+// a key rotation do not collide with the main and the sub listeners.
+func (cfg *GuardianConfig) validateRotationPorts() error {
+	if cfg.rotationPort == 0 {
+		return nil
+	}
+	listen := []string{cfg.serverConfig.ListenAddr}
+	if cfg.serverConfig.EnableMultiChannelServer {
+		listen = append(listen, cfg.serverConfig.SubListenAddr...)
+	}
+	used := make(map[string]string)
+	for _, addr := range listen {
+		if _, port, err := net.SplitHostPort(addr); err == nil && port != "0" {
+			used[port] = addr
+		}
+	}
+	rotation := append([]string{fmt.Sprintf(":%d", cfg.rotationPort)}, cfg.rotationSubListenAddr()...)
+	for _, addr := range rotation {
+		_, port, _ := net.SplitHostPort(addr)
+		if listener, ok := used[port]; ok {
+			return fmt.Errorf("--%s %d: port %s of the rotated identity is already used by the listen address %s",
+				flags.RotationPortFlag.Name, cfg.rotationPort, port, listener)
+		}
+	}
+	if last := cfg.rotationPort + len(rotation) - 1; last > 65535 {
+		return fmt.Errorf("--%s %d: port %d of the rotated identity is out of range", flags.RotationPortFlag.Name, cfg.rotationPort, last)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import "testing"
+
+func TestValidateRotationPorts(t *testing.T) {
+	tests := []struct {
+		rotationPort int
+		multiChannel bool
+		ok           bool
+	}{
+		{0, true, true},       // Random ports
+		{32320, true, true},   // 32320 and 32321
+		{32323, false, false}, // Main port
+		{32324, false, true},  // Sub port, not used without multi-channel
+		{32324, true, false},  // Sub port
+		{32322, true, false},  // Rotation sub port 32323 is the main port
+		{32325, true, true},   // 32325 and 32326
+		{65535, false, true},  // Last port
+		{65535, true, false},  // Rotation sub port out of range
+	}
+	for _, test := range tests {
+		cfg := &GuardianConfig{rotationPort: test.rotationPort}
+		cfg.serverConfig.ListenAddr = ":32323"
+		cfg.serverConfig.SubListenAddr = []string{":32324"}
+		cfg.serverConfig.EnableMultiChannelServer = test.multiChannel
+
+		err := cfg.validateRotationPorts()
+		if (err == nil) != test.ok {
+			t.Errorf("rotation port %d, multi-channel %v: error %v, want ok %v", test.rotationPort, test.multiChannel, err, test.ok)
+		}
+	}
+}
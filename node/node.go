@@ -22,10 +22,13 @@ package node
 
 import (
 	"net"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/klaytn/klaytn/log"
 	"github.com/klaytn/klaytn/networks/p2p"
+	"github.com/klaytn/klaytn/networks/p2p/discover"
 	"github.com/klaytn/klaytn/networks/p2p/nat"
 	"github.com/klaytn/klaytn/networks/rpc"
 	"github.com/klaytn/klaytn/node"
@@ -37,7 +40,9 @@ var logger = log.NewModuleLogger(log.Node)
 type Node struct {
 	config *GuardianConfig
 
-	server p2p.Server
+	server   p2p.Server
+	retiring p2p.Server // Server of the previous identity during a key rotation
+	rotated  bool       // Whether the node key was rotated since Start
 
 	rpcAPIs       []rpc.API
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
@@ -46,6 +51,17 @@ type Node struct {
 	ipcListener net.Listener // IPC RPC listener socket to serve API requests
 	ipcHandler  *rpc.Server  // IPC RPC request handler to process the API requests
 
+	audit        *AuditLog // Log of administrative actions, nil without a datadir
+	staticPeers  *peerList // Static peers added at runtime
+	trustedPeers *peerList // Trusted peers added at runtime
+	tracker      *peerTracker
+	history      *peerHistory // Durable peer events, nil without a datadir
+	alerter      *Alerter     // Webhook alerts, nil if not configured
+	alertMonitor *alertMonitor
+	rules        *ruleEngine // Alert rules, nil if there are none
+	peerTypes    *peerTypeEnforcer
+	dials        *dialTracker // Dialer of the p2p servers
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
@@ -61,14 +77,17 @@ func New(conf *GuardianConfig) (*Node, error) {
 
 	// Note: any interaction with Config that would create/touch files
 	// in the data directory or instance directory is delayed until Start.
+	dials := newDialTracker()
+	conf.serverConfig.Dialer = dials
 	return &Node{
 		config:      conf,
 		ipcEndpoint: conf.IPCEndpoint(),
+		dials:       dials,
 		logger:      conf.Logger,
 	}, nil
 }
 
-func (n *Node) Start() error {
+func (n *Node) Start() (err error) {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 	if n.server != nil {
@@ -96,20 +115,88 @@ func (n *Node) Start() error {
 		}
 	}
 
-	n.server = p2p.NewServer(n.config.serverConfig)
-	n.logger.Info("Starting peer-to-peer node", "instance", n.config.serverConfig.Name)
+	if n.config.DataDir != "" {
+		if err := os.MkdirAll(n.config.DataDir, 0o700); err != nil {
+			return err
+		}
+	}
+	// Release what was opened so far if the startup fails.
+	defer func() {
+		if err != nil {
+			n.rpcAPIs = nil
+			if n.tracker != nil {
+				n.tracker.stop()
+				n.tracker = nil
+			}
+			if n.server != nil {
+				n.server.Stop()
+				n.server = nil
+			}
+			n.closeDatadir()
+			if n.alerter != nil {
+				n.alerter.Stop()
+				n.alerter = nil
+			}
+		}
+	}()
+	if path := n.config.datadirFile(DatadirAuditLog); path != "" {
+		if n.audit, err = OpenAuditLog(path); err != nil {
+			return err
+		}
+	}
+	if n.staticPeers, err = loadPeerList(n.config.datadirFile(datadirStaticPeers)); err != nil {
+		return err
+	}
+	if n.trustedPeers, err = loadPeerList(n.config.datadirFile(datadirTrustedPeers)); err != nil {
+		return err
+	}
+	if path := n.config.datadirFile(datadirPeerHistory); path != "" {
+		if n.history, err = openPeerHistory(path, n.config.peerHistoryRetention, n.config.peerHistoryMaxEntries); err != nil {
+			return err
+		}
+	}
+
+	rules, err := parseAlertRules(n.config.alerts)
+	if err != nil {
+		return err
+	}
+	if n.config.alerts.Webhook != "" {
+		if n.alerter, err = NewAlerter(n.config.alerts); err != nil {
+			return err
+		}
+	}
+
+	serverConfig := n.config.serverConfig
+	serverConfig.StaticNodes = mergeNodes(serverConfig.StaticNodes, n.staticPeers.Nodes())
+	serverConfig.TrustedNodes = n.trustedNodes()
+	n.server = p2p.NewServer(serverConfig)
+	n.rotated = false
+	n.logger.Info("Starting peer-to-peer node", "instance", serverConfig.Name)
 
 	if err := n.server.Start(); err != nil {
+		n.server = nil
 		return convertFileLockError(err)
 	}
+	n.tracker = newPeerTracker(n.config.AuthorizedNodes, n.history)
+	n.tracker.track(n.server)
 
 	n.appendAPIs(n.APIs())
-
-	// Lastly start the configured RPC interfaces
 	if err := n.startRPC(); err != nil {
 		return err
 	}
 
+	// Lastly start the workers following the peers. Nothing fails after
+	// them, so the cleanup above does not need to stop them.
+	if n.alerter != nil {
+		n.alertMonitor = newAlertMonitor(n.alerter, n.tracker)
+	}
+	if len(rules) > 0 {
+		n.rules = newRuleEngine(rules, n.tracker, n.alerter)
+	}
+	if n.config.peerTypePolicy != nil {
+		n.peerTypes = newPeerTypeEnforcer(n, n.config.peerTypePolicy, n.tracker)
+	}
+
 	// Finish initializing the startup
 	n.stop = make(chan struct{})
 
@@ -122,10 +209,10 @@ func (n *Node) Start() error {
 func (n *Node) startRPC() error {
 	apis := n.apis()
 	// Start the various API endpoints, terminating all in case of errors
-	if err := n.startInProc(apis); err != nil {
+	if err := n.startInProc(withTransport(apis, TransportInProc)); err != nil {
 		return err
 	}
-	if err := n.startIPC(apis); err != nil {
+	if err := n.startIPC(withTransport(apis, TransportIPC)); err != nil {
 		n.stopInProc()
 		return err
 	}
@@ -190,12 +277,43 @@ func (n *Node) stopIPC() {
 // Stop terminates a running node along with all it's services. In the node was
 // not started, an error is returned.
 func (n *Node) Stop() error {
+	// The peer type enforcer reads the peers under the node lock, so wait for
+	// it before taking the lock for the rest of the shutdown.
+	n.lock.Lock()
+	peerTypes := n.peerTypes
+	n.peerTypes = nil
+	n.lock.Unlock()
+	if peerTypes != nil {
+		peerTypes.stop()
+	}
+
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
 	// Terminate the API, services and the p2p server.
 	n.stopIPC()
 	n.rpcAPIs = nil
+	if n.retiring != nil {
+		n.retiring.Stop()
+		n.retiring = nil
+	}
+	if n.rules != nil {
+		n.rules.stop()
+		n.rules = nil
+	}
+	if n.alertMonitor != nil {
+		n.alertMonitor.stop()
+		n.alertMonitor = nil
+	}
+	if n.alerter != nil {
+		n.alerter.Stop()
+		n.alerter = nil
+	}
+	if n.tracker != nil {
+		n.tracker.stop()
+		n.tracker = nil
+	}
+	n.closeDatadir()
 
 	// unblock n.Wait
 	close(n.stop)
@@ -203,6 +321,22 @@ func (n *Node) Stop() error {
 	return nil
 }
 
+// closeDatadir closes the peer history and the audit log.
+func (n *Node) closeDatadir() {
+	if n.history != nil {
+		if err := n.history.close(); err != nil {
+			n.logger.Error("Failed to close peer history", "err", err)
+		}
+		n.history = nil
+	}
+	if n.audit != nil {
+		if err := n.audit.Close(); err != nil {
+			n.logger.Error("Failed to close audit log", "err", err)
+		}
+		n.audit = nil
+	}
+}
+
 // Wait blocks the thread until the node is stopped. If the node is not running
 // at the time of invocation, the method immediately returns.
 func (n *Node) Wait() {
@@ -255,6 +389,161 @@ func (n *Node) IPCEndpoint() string {
 	return n.ipcEndpoint
 }
 
+// servers returns the p2p server and, during a key rotation, the server of the
+// retiring identity.
+func (n *Node) servers() []p2p.Server {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	var servers []p2p.Server
+	for _, server := range []p2p.Server{n.server, n.retiring} {
+		if server != nil {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// StaticPeers returns the configured static peers and those added at runtime.
+func (n *Node) StaticPeers() []*discover.Node {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.staticPeers == nil {
+		return n.config.serverConfig.StaticNodes
+	}
+	return mergeNodes(n.config.serverConfig.StaticNodes, n.staticPeers.Nodes())
+}
+
+// addStaticPeer remembers a peer added at runtime across restarts.
+func (n *Node) addStaticPeer(node *discover.Node) error {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.staticPeers == nil {
+		return ErrNodeStopped
+	}
+	return n.staticPeers.Add(node)
+}
+
+// removeStaticPeer forgets a peer added at runtime.
+func (n *Node) removeStaticPeer(node *discover.Node) error {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.staticPeers == nil {
+		return ErrNodeStopped
+	}
+	return n.staticPeers.Remove(node)
+}
+
+// TrustedPeers returns the configured trusted peers, the authorized nodes and
+// the trusted peers added at runtime.
+func (n *Node) TrustedPeers() []*discover.Node {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.trustedNodes()
+}
+
+// trustedNodes returns the trusted nodes. Authorized nodes are always trusted
+// so that they are not subject to the connection limits.
+func (n *Node) trustedNodes() []*discover.Node {
+	var runtime []*discover.Node
+	if n.trustedPeers != nil {
+		runtime = n.trustedPeers.Nodes()
+	}
+	return mergeNodes(n.config.serverConfig.TrustedNodes, n.config.AuthorizedNodes, runtime)
+}
+
+// addTrustedPeer remembers a trusted peer added at runtime across restarts.
+func (n *Node) addTrustedPeer(node *discover.Node) error {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.trustedPeers == nil {
+		return ErrNodeStopped
+	}
+	return n.trustedPeers.Add(node)
+}
+
+// removeTrustedPeer forgets a trusted peer added at runtime.
+func (n *Node) removeTrustedPeer(node *discover.Node) error {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.trustedPeers == nil {
+		return ErrNodeStopped
+	}
+	return n.trustedPeers.Remove(node)
+}
+
+// peerTracker returns the tracker of the peers of the running node.
+func (n *Node) peerTracker() *peerTracker {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.tracker
+}
+
+// PeerHistory returns the peer connects and disconnects observed in [from, to),
+// optionally of a single peer.
+func (n *Node) PeerHistory(from, to time.Time, peer *discover.NodeID) ([]*GuardianPeerEvent, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.server == nil {
+		return nil, ErrNodeStopped
+	}
+	if n.history == nil {
+		return nil, ErrNoPeerHistory
+	}
+	return n.history.query(from, to, peer)
+}
+
+// Alerts returns the alert rules whose condition currently holds.
+func (n *Node) Alerts() ([]*ActiveAlert, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.server == nil {
+		return nil, ErrNodeStopped
+	}
+	if n.rules == nil {
+		return []*ActiveAlert{}, nil
+	}
+	return n.rules.active(), nil
+}
+
+// auditAction appends an administrative action to the audit log.
+func (n *Node) auditAction(transport, action string, args []interface{}, result interface{}, err error) {
+	n.lock.RLock()
+	audit := n.audit
+	n.lock.RUnlock()
+
+	if audit == nil {
+		return
+	}
+	if auditErr := audit.Append(transport, action, args, result, err); auditErr != nil {
+		n.logger.Error("Failed to write audit log", "action", action, "err", auditErr)
+	}
+}
+
+// withTransport returns a copy of apis whose private admin service records
+// the transport in the audit log.
+func withTransport(apis []rpc.API, transport string) []rpc.API {
+	copied := make([]rpc.API, len(apis))
+	copy(copied, apis)
+	for i, api := range copied {
+		if admin, ok := api.Service.(*PrivateGuardianAdminAPI); ok {
+			service := *admin
+			service.transport = transport
+			copied[i].Service = &service
+		}
+	}
+	return copied
+}
+
 func (n *Node) appendAPIs(apis []rpc.API) {
 	n.rpcAPIs = append(n.rpcAPIs, apis...)
 }
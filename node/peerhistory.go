@@ -0,0 +1,197 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	datadirPeerHistory = "peerhistory" // Database of peer connects and disconnects
+
+	peerHistoryPruneInterval = time.Hour
+	peerHistoryQueryLimit    = 10000 // Maximum number of events returned by a query
+)
+
+var ErrNoPeerHistory = errors.New("peer history requires a data directory")
+
+// peerHistory stores the connect and disconnect events of the peers in a
+// leveldb database keyed by time, pruning them by age and count.
+type peerHistory struct {
+	db         *leveldb.DB
+	retention  time.Duration // Maximum age of the events, 0 = no limit
+	maxEntries int           // Maximum number of events, 0 = no limit
+	count      int           // Number of stored events
+	seq        uint64        // Distinguishes events observed at the same time
+
+	lock sync.Mutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func openPeerHistory(path string, retention time.Duration, maxEntries int) (*peerHistory, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	h := &peerHistory{
+		db:         db,
+		retention:  retention,
+		maxEntries: maxEntries,
+		quit:       make(chan struct{}),
+	}
+
+	it := db.NewIterator(nil, nil)
+	for it.Next() {
+		h.count++
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	h.prune()
+
+	h.wg.Add(1)
+	go h.loop()
+	return h, nil
+}
+
+// peerHistoryKey returns the database key of an event observed at t.
+func peerHistoryKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+// add stores an event. Failures are logged, as losing history must not
+// disturb the p2p server.
+func (h *peerHistory) add(event *GuardianPeerEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("Failed to encode peer event", "err", err)
+		return
+	}
+	h.seq++
+	if err := h.db.Put(peerHistoryKey(event.Time, h.seq), value, nil); err != nil {
+		logger.Error("Failed to store peer event", "err", err)
+		return
+	}
+	h.count++
+	if h.maxEntries > 0 && h.count > h.maxEntries {
+		h.pruneLocked()
+	}
+}
+
+// query returns the events observed in [from, to), oldest first, optionally of
+// a single peer. At most peerHistoryQueryLimit events are returned.
+func (h *peerHistory) query(from, to time.Time, peer *discover.NodeID) ([]*GuardianPeerEvent, error) {
+	if epoch := time.Unix(0, 0); from.Before(epoch) {
+		from = epoch
+	}
+	events := []*GuardianPeerEvent{}
+	it := h.db.NewIterator(&util.Range{Start: peerHistoryKey(from, 0), Limit: peerHistoryKey(to, 0)}, nil)
+	defer it.Release()
+
+	for it.Next() && len(events) < peerHistoryQueryLimit {
+		var event GuardianPeerEvent
+		if err := json.Unmarshal(it.Value(), &event); err != nil {
+			return nil, err
+		}
+		if peer != nil && event.Peer != *peer {
+			continue
+		}
+		events = append(events, &event)
+	}
+	return events, it.Error()
+}
+
+// prune deletes the events older than the retention period and the oldest
+// events beyond the maximum number of entries. The maximum is also kept by
+// add; the periodic prune enforces the retention period.
+func (h *peerHistory) prune() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.pruneLocked()
+}
+
+func (h *peerHistory) pruneLocked() {
+	var (
+		limit   = time.Now().Add(-h.retention)
+		batch   = new(leveldb.Batch)
+		removed int
+	)
+	it := h.db.NewIterator(nil, nil)
+	for it.Next() {
+		expired := h.retention > 0 && int64(binary.BigEndian.Uint64(it.Key()[:8])) < limit.UnixNano()
+		excess := h.maxEntries > 0 && h.count-removed > h.maxEntries
+		if !expired && !excess {
+			break
+		}
+		batch.Delete(append([]byte{}, it.Key()...))
+		removed++
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		logger.Error("Failed to prune peer history", "err", err)
+		return
+	}
+	if removed == 0 {
+		return
+	}
+	if err := h.db.Write(batch, nil); err != nil {
+		logger.Error("Failed to prune peer history", "err", err)
+		return
+	}
+	h.count -= removed
+	logger.Debug("Pruned peer history", "removed", removed, "kept", h.count)
+}
+
+func (h *peerHistory) loop() {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(peerHistoryPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.prune()
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// close stops pruning and closes the database.
+func (h *peerHistory) close() error {
+	close(h.quit)
+	h.wg.Wait()
+	return h.db.Close()
+}
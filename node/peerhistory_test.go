@@ -0,0 +1,99 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klaytn/klaytn/networks/p2p"
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+// storedPeers returns the peers of the stored events, oldest first.
+func storedPeers(t *testing.T, h *peerHistory) []byte {
+	events, err := h.query(time.Time{}, time.Now().Add(time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers := make([]byte, len(events))
+	for i, event := range events {
+		peers[i] = event.Peer[0]
+	}
+	return peers
+}
+
+func TestPeerHistoryPrune(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		retention  time.Duration
+		maxEntries int
+		ages       []time.Duration // Of the added events, oldest first
+		want       string          // Peers of the events kept after a prune
+	}{
+		{0, 0, []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour}, "\x01\x02\x03"},
+		{90 * time.Minute, 0, []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour, 0}, "\x03\x04"},
+		{0, 2, []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour, 0}, "\x03\x04"},
+		{150 * time.Minute, 3, []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour, 0}, "\x02\x03\x04"},
+		{90 * time.Minute, 3, []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour, 0}, "\x03\x04"},
+	}
+	for i, test := range tests {
+		h, err := openPeerHistory(filepath.Join(t.TempDir(), datadirPeerHistory), test.retention, test.maxEntries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, age := range test.ages {
+			h.add(&GuardianPeerEvent{
+				PeerEvent: &p2p.PeerEvent{Type: p2p.PeerEventTypeAdd, Peer: discover.NodeID{byte(j + 1)}},
+				Time:      now.Add(-age),
+			})
+		}
+		h.prune()
+
+		if got := string(storedPeers(t, h)); got != test.want {
+			t.Errorf("test %d: kept peers %q, want %q", i, got, test.want)
+		}
+		if h.count != len(test.want) {
+			t.Errorf("test %d: count %d, want %d", i, h.count, len(test.want))
+		}
+		h.close()
+	}
+}
+
+func TestPeerHistoryMaxEntriesOnAdd(t *testing.T) {
+	h, err := openPeerHistory(filepath.Join(t.TempDir(), datadirPeerHistory), 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.close()
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		h.add(&GuardianPeerEvent{
+			PeerEvent: &p2p.PeerEvent{Type: p2p.PeerEventTypeAdd, Peer: discover.NodeID{byte(i + 1)}},
+			Time:      now.Add(time.Duration(i) * time.Second),
+		})
+		if h.count > 3 {
+			t.Fatalf("%d events stored after %d adds, the maximum is 3", h.count, i+1)
+		}
+	}
+	// Without a prune, only the newest events are kept
+	if got, want := string(storedPeers(t, h)), "\x08\x09\x0a"; got != want {
+		t.Errorf("kept peers %q, want %q", got, want)
+	}
+}
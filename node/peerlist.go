@@ -0,0 +1,168 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+const (
+	datadirStaticPeers  = "static-peers.json"  // Peers added with admin_addPeer
+	datadirTrustedPeers = "trusted-peers.json" // Peers added with admin_addTrustedPeer
+)
+
+// peerList is a set of nodes kept in a JSON array of kni URLs, so that peers
+// added at runtime survive a restart. Without a path it is kept in memory.
+type peerList struct {
+	path  string
+	nodes []*discover.Node
+	lock  sync.Mutex
+}
+
+// loadPeerList reads the peer list at path. A missing file is an empty list.
+func loadPeerList(path string) (*peerList, error) {
+	l := &peerList{path: path}
+	if path == "" {
+		return l, nil
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	if err := json.Unmarshal(content, &urls); err != nil {
+		return nil, fmt.Errorf("invalid peer list %s: %v", path, err)
+	}
+	for _, url := range urls {
+		node, err := discover.ParseNode(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kni %s in %s: %v", url, path, err)
+		}
+		l.nodes = append(l.nodes, node)
+	}
+	return l, nil
+}
+
+// Nodes returns a copy of the nodes in the list.
+func (l *peerList) Nodes() []*discover.Node {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return append([]*discover.Node{}, l.nodes...)
+}
+
+// Add adds the node, or replaces the entry with the same ID, and saves the list.
+func (l *peerList) Add(node *discover.Node) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for i, n := range l.nodes {
+		if n.ID == node.ID {
+			if n.String() == node.String() {
+				return nil
+			}
+			l.nodes[i] = node
+			return l.save()
+		}
+	}
+	l.nodes = append(l.nodes, node)
+	return l.save()
+}
+
+// Remove removes the node with the same ID, if any, and saves the list.
+func (l *peerList) Remove(node *discover.Node) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for i, n := range l.nodes {
+		if n.ID == node.ID {
+			l.nodes = append(l.nodes[:i], l.nodes[i+1:]...)
+			return l.save()
+		}
+	}
+	return nil
+}
+
+// save writes the list to a temporary file which replaces the previous one.
+func (l *peerList) save() error {
+	if l.path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(nodeURLs(l.nodes), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
+
+// mergeNodes returns the nodes of all lists, leaving out repeated IDs.
+func mergeNodes(lists ...[]*discover.Node) []*discover.Node {
+	var (
+		merged []*discover.Node
+		seen   = make(map[discover.NodeID]bool)
+	)
+	for _, list := range lists {
+		for _, node := range list {
+			if !seen[node.ID] {
+				seen[node.ID] = true
+				merged = append(merged, node)
+			}
+		}
+	}
+	return merged
+}
+
+// containsNode reports whether a node with the given ID is in the list.
+func containsNode(nodes []*discover.Node, id discover.NodeID) bool {
+	for _, node := range nodes {
+		if node.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeURLs returns the kni URLs of the nodes.
+func nodeURLs(nodes []*discover.Node) []string {
+	urls := make([]string, len(nodes))
+	for i, n := range nodes {
+		urls[i] = n.String()
+	}
+	return urls
+}
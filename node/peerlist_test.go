@@ -0,0 +1,196 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/klaytn/klaytn/networks/p2p"
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+// testNode returns a node with an ID derived from id, listening on port.
+func testNode(t *testing.T, id byte, port int) *discover.Node {
+	var nodeID discover.NodeID
+	nodeID[0] = id
+	node, err := discover.ParseNode(fmt.Sprintf("kni://%s@127.0.0.1:%d?discport=0", nodeID, port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node
+}
+
+func TestPeerListSaveLoad(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		path = filepath.Join(dir, datadirStaticPeers)
+		n1   = testNode(t, 1, 32323)
+		n1b  = testNode(t, 1, 32324) // Same ID, other port
+		n2   = testNode(t, 2, 32323)
+		n3   = testNode(t, 3, 32323)
+	)
+	tests := []struct {
+		op   func(l *peerList) error
+		want []*discover.Node
+	}{
+		{func(l *peerList) error { return l.Add(n1) }, []*discover.Node{n1}},
+		{func(l *peerList) error { return l.Add(n2) }, []*discover.Node{n1, n2}},
+		{func(l *peerList) error { return l.Add(n1) }, []*discover.Node{n1, n2}},   // Repeated
+		{func(l *peerList) error { return l.Add(n1b) }, []*discover.Node{n1b, n2}}, // Replaced
+		{func(l *peerList) error { return l.Add(n3) }, []*discover.Node{n1b, n2, n3}},
+		{func(l *peerList) error { return l.Remove(n2) }, []*discover.Node{n1b, n3}},
+		{func(l *peerList) error { return l.Remove(n2) }, []*discover.Node{n1b, n3}}, // Not in the list
+		{func(l *peerList) error { return l.Remove(n1) }, []*discover.Node{n3}},      // By ID
+	}
+	for i, test := range tests {
+		// Each step starts from the list saved by the previous one
+		l, err := loadPeerList(path)
+		if err != nil {
+			t.Fatalf("step %d: load: %v", i, err)
+		}
+		if err := test.op(l); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if got := nodeURLs(l.Nodes()); !reflect.DeepEqual(got, nodeURLs(test.want)) {
+			t.Errorf("step %d: list %v, want %v", i, got, nodeURLs(test.want))
+		}
+		loaded, err := loadPeerList(path)
+		if err != nil {
+			t.Fatalf("step %d: reload: %v", i, err)
+		}
+		if got := nodeURLs(loaded.Nodes()); !reflect.DeepEqual(got, nodeURLs(test.want)) {
+			t.Errorf("step %d: saved list %v, want %v", i, got, nodeURLs(test.want))
+		}
+	}
+
+	// The temporary files of the saves are gone
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != datadirStaticPeers {
+		t.Errorf("unexpected files in the datadir: %v", entries)
+	}
+}
+
+func TestLoadPeerList(t *testing.T) {
+	tests := []struct {
+		content string // Empty for a missing file
+		count   int
+		ok      bool
+	}{
+		{"", 0, true},
+		{"[]", 0, true},
+		{fmt.Sprintf("[%q]", testNode(t, 1, 32323)), 1, true},
+		{"{}", 0, false},
+		{`["enode://1234@127.0.0.1:30303"]`, 0, false},
+	}
+	for i, test := range tests {
+		path := filepath.Join(t.TempDir(), datadirStaticPeers)
+		if test.content != "" {
+			if err := os.WriteFile(path, []byte(test.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		l, err := loadPeerList(path)
+		if (err == nil) != test.ok {
+			t.Errorf("test %d: error %v, want ok %v", i, err, test.ok)
+			continue
+		}
+		if err == nil && len(l.Nodes()) != test.count {
+			t.Errorf("test %d: %d nodes, want %d", i, len(l.Nodes()), test.count)
+		}
+	}
+}
+
+func TestMergeNodes(t *testing.T) {
+	var (
+		n1  = testNode(t, 1, 32323)
+		n1b = testNode(t, 1, 32324)
+		n2  = testNode(t, 2, 32323)
+		n3  = testNode(t, 3, 32323)
+	)
+	tests := []struct {
+		lists [][]*discover.Node
+		want  []*discover.Node
+	}{
+		{nil, nil},
+		{[][]*discover.Node{{n1, n2}}, []*discover.Node{n1, n2}},
+		{[][]*discover.Node{{n1, n1}}, []*discover.Node{n1}},
+		{[][]*discover.Node{{n1}, {n2}, {n3}}, []*discover.Node{n1, n2, n3}},
+		{[][]*discover.Node{{n1, n2}, {n2, n3}}, []*discover.Node{n1, n2, n3}},
+		{[][]*discover.Node{{n1b}, {n1}}, []*discover.Node{n1b}}, // The first entry of an ID wins
+		{[][]*discover.Node{nil, {n2}, nil}, []*discover.Node{n2}},
+	}
+	for i, test := range tests {
+		if got := mergeNodes(test.lists...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("test %d: merged %v, want %v", i, nodeURLs(got), nodeURLs(test.want))
+		}
+	}
+}
+
+// staticPeerServer is a p2p server that records its static peers.
+type staticPeerServer struct {
+	p2p.Server
+	static map[discover.NodeID]bool
+}
+
+func (s *staticPeerServer) AddPeer(node *discover.Node)    { s.static[node.ID] = true }
+func (s *staticPeerServer) RemovePeer(node *discover.Node) { delete(s.static, node.ID) }
+
+func TestAdminPeersPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), datadirStaticPeers)
+	staticPeers, err := loadPeerList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &staticPeerServer{static: make(map[discover.NodeID]bool)}
+	api := NewPrivateGuardianAdminAPI(&Node{server: server, staticPeers: staticPeers})
+
+	n1, n2 := testNode(t, 1, 32323), testNode(t, 2, 32323)
+	tests := []struct {
+		op   func() (bool, error)
+		want []*discover.Node
+	}{
+		{func() (bool, error) { return api.AddPeer(n1.String()) }, []*discover.Node{n1}},
+		{func() (bool, error) { return api.AddPeer(n2.String()) }, []*discover.Node{n1, n2}},
+		{func() (bool, error) { return api.RemovePeer(n1.String()) }, []*discover.Node{n2}},
+		{func() (bool, error) { return api.RemovePeer(n1.String()) }, []*discover.Node{n2}},
+	}
+	for i, test := range tests {
+		if ok, err := test.op(); !ok || err != nil {
+			t.Fatalf("step %d: ok %v, error %v", i, ok, err)
+		}
+		loaded, err := loadPeerList(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := nodeURLs(loaded.Nodes()); !reflect.DeepEqual(got, nodeURLs(test.want)) {
+			t.Errorf("step %d: persisted %v, want %v", i, got, nodeURLs(test.want))
+		}
+		if len(server.static) != len(test.want) {
+			t.Errorf("step %d: %d static peers in the server, want %d", i, len(server.static), len(test.want))
+		}
+	}
+	if _, err := api.AddPeer("kni://invalid"); err == nil {
+		t.Error("added an invalid kni")
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/klaytn/klaytn/common"
+	"github.com/klaytn/klaytn/networks/p2p"
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+// Sides of a peer connection: authorized nodes sit behind the guardian and
+// every other peer is on the public side.
+const (
+	PeerSideAuthorized = "authorized"
+	PeerSidePublic     = "public"
+)
+
+// PeerTraffic counts the messages and bytes exchanged with a peer.
+type PeerTraffic struct {
+	MsgsIn   uint64 `json:"msgsIn"`
+	MsgsOut  uint64 `json:"msgsOut"`
+	BytesIn  uint64 `json:"bytesIn"`
+	BytesOut uint64 `json:"bytesOut"`
+}
+
+// GuardianPeerInfo extends p2p.PeerInfo with what the guardian knows about
+// the peer.
+type GuardianPeerInfo struct {
+	*p2p.PeerInfo
+	Side         string      `json:"side"`
+	NodeType     string      `json:"nodeType"`
+	ConnectedAt  time.Time   `json:"connectedAt"`
+	ConnectedFor string      `json:"connectedFor"`
+	Static       bool        `json:"static"`
+	Trusted      bool        `json:"trusted"`
+	Traffic      PeerTraffic `json:"traffic"`
+}
+
+// nodeTypeName returns the short name of a klaytn node type.
+func nodeTypeName(connType common.ConnType) string {
+	switch connType {
+	case common.CONSENSUSNODE:
+		return "cn"
+	case common.PROXYNODE:
+		return "pn"
+	case common.ENDPOINTNODE:
+		return "en"
+	case common.BOOTNODE:
+		return "bn"
+	}
+	return "unknown"
+}
+
+// peerEventHistory is the number of connect and disconnect events kept for
+// replay to new PeerEvents subscribers.
+const peerEventHistory = 1024
+
+// GuardianPeerEvent is a p2p.PeerEvent with the time it was observed and the
+// side of the peer.
+type GuardianPeerEvent struct {
+	*p2p.PeerEvent
+	Time time.Time `json:"time"`
+	Side string    `json:"side"`
+
+	// Connected tells whether the peer is connected after the event. A drop
+	// leaves it connected if it still has a connection to another server,
+	// as during a node key rotation.
+	Connected bool `json:"-"`
+}
+
+// trackedPeer is the state kept for a connected peer. During a node key
+// rotation a peer can be connected to both the live and the retiring server,
+// so the connections are counted and the peer is forgotten with the last one.
+type trackedPeer struct {
+	connectedAt time.Time
+	conns       int
+	traffic     PeerTraffic
+}
+
+// peerTracker follows the events of the p2p servers to keep the connection
+// time and the traffic of every peer. It keeps the recent connect and
+// disconnect events and passes the events on to its subscribers.
+type peerTracker struct {
+	authorized map[discover.NodeID]bool
+	peers      map[discover.NodeID]*trackedPeer
+	history    []*GuardianPeerEvent // Ring buffer of connect and disconnect events
+	next       int                  // Position of the next event in history
+	store      *peerHistory         // Durable history, nil without a datadir
+
+	// Totals since the start, exposed to the alert rules
+	traffic       PeerTraffic
+	connects      uint64
+	disconnects   uint64
+	droppedEvents uint64 // Events not delivered to slow subscribers
+
+	// Subscribers and the filters of the events they receive
+	subs map[chan *GuardianPeerEvent]func(*GuardianPeerEvent) bool
+
+	lock sync.Mutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newPeerTracker(authorized []*discover.Node, store *peerHistory) *peerTracker {
+	return &peerTracker{
+		authorized: nodeIDSet(authorized),
+		store:      store,
+		peers:      make(map[discover.NodeID]*trackedPeer),
+		history:    make([]*GuardianPeerEvent, 0, peerEventHistory),
+		subs:       make(map[chan *GuardianPeerEvent]func(*GuardianPeerEvent) bool),
+		quit:       make(chan struct{}),
+	}
+}
+
+// track starts following the events of server until it or the tracker stops.
+func (t *peerTracker) track(server p2p.Server) {
+	events := make(chan *p2p.PeerEvent, 256)
+	sub := server.SubscribeEvents(events)
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case event := <-events:
+				t.handle(event)
+			case <-sub.Err():
+				return
+			case <-t.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (t *peerTracker) handle(event *p2p.PeerEvent) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	observed := &GuardianPeerEvent{PeerEvent: event, Time: time.Now(), Side: PeerSidePublic}
+	if t.authorized[event.Peer] {
+		observed.Side = PeerSideAuthorized
+	}
+	switch event.Type {
+	case p2p.PeerEventTypeAdd:
+		if peer := t.peers[event.Peer]; peer != nil {
+			peer.conns++
+		} else {
+			t.peers[event.Peer] = &trackedPeer{connectedAt: observed.Time, conns: 1}
+		}
+		t.connects++
+		t.record(observed)
+	case p2p.PeerEventTypeDrop:
+		if peer := t.peers[event.Peer]; peer != nil {
+			if peer.conns--; peer.conns <= 0 {
+				delete(t.peers, event.Peer)
+			}
+		}
+		t.disconnects++
+		t.record(observed)
+	case p2p.PeerEventTypeMsgSend, p2p.PeerEventTypeMsgRecv:
+		var size uint64
+		if event.MsgSize != nil {
+			size = uint64(*event.MsgSize)
+		}
+		counters := []*PeerTraffic{&t.traffic}
+		if peer := t.peers[event.Peer]; peer != nil {
+			counters = append(counters, &peer.traffic)
+		}
+		for _, traffic := range counters {
+			if event.Type == p2p.PeerEventTypeMsgSend {
+				traffic.MsgsOut++
+				traffic.BytesOut += size
+			} else {
+				traffic.MsgsIn++
+				traffic.BytesIn += size
+			}
+		}
+	}
+
+	// Publish after the update, so that subscribers see whether the peer is
+	// still connected through another server
+	observed.Connected = t.peers[event.Peer] != nil
+	for sub, match := range t.subs {
+		if !match(observed) {
+			continue
+		}
+		select {
+		case sub <- observed:
+		default:
+			t.droppedEvents++
+			logger.Warn("Dropped peer event for a slow subscriber", "type", event.Type, "peer", event.Peer)
+		}
+	}
+}
+
+// record adds an event to the history, replacing the oldest one when full,
+// and to the durable history.
+func (t *peerTracker) record(event *GuardianPeerEvent) {
+	if t.store != nil {
+		t.store.add(event)
+	}
+	if len(t.history) < peerEventHistory {
+		t.history = append(t.history, event)
+		return
+	}
+	t.history[t.next] = event
+	t.next = (t.next + 1) % peerEventHistory
+}
+
+// subscribe returns the recorded events matching the filter, oldest first, and
+// a channel receiving the matching events from now on. The channel is closed
+// when the tracker stops.
+func (t *peerTracker) subscribe(match func(*GuardianPeerEvent) bool) ([]*GuardianPeerEvent, chan *GuardianPeerEvent) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var history []*GuardianPeerEvent
+	for _, events := range [][]*GuardianPeerEvent{t.history[t.next:], t.history[:t.next]} {
+		for _, event := range events {
+			if match(event) {
+				history = append(history, event)
+			}
+		}
+	}
+
+	sub := make(chan *GuardianPeerEvent, 256)
+	if t.subs != nil {
+		t.subs[sub] = match
+	} else {
+		close(sub)
+	}
+	return history, sub
+}
+
+// unsubscribe stops delivering events to a channel returned by subscribe.
+func (t *peerTracker) unsubscribe(sub chan *GuardianPeerEvent) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.subs[sub]; ok {
+		delete(t.subs, sub)
+		close(sub)
+	}
+}
+
+// peer returns a copy of the state of a peer, or nil if it is not tracked.
+func (t *peerTracker) peer(id discover.NodeID) *trackedPeer {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if peer := t.peers[id]; peer != nil {
+		copied := *peer
+		return &copied
+	}
+	return nil
+}
+
+// metrics returns the current values of the metrics used by the alert rules.
+func (t *peerTracker) metrics() map[string]float64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	authorized := 0
+	for id := range t.peers {
+		if t.authorized[id] {
+			authorized++
+		}
+	}
+	return map[string]float64{
+		"peers":            float64(len(t.peers)),
+		"authorized_peers": float64(authorized),
+		"public_peers":     float64(len(t.peers) - authorized),
+		"peer_connects":    float64(t.connects),
+		"peer_disconnects": float64(t.disconnects),
+		"messages_in":      float64(t.traffic.MsgsIn),
+		"messages_out":     float64(t.traffic.MsgsOut),
+		"bytes_in":         float64(t.traffic.BytesIn),
+		"bytes_out":        float64(t.traffic.BytesOut),
+		"dropped_events":   float64(t.droppedEvents),
+	}
+}
+
+// stop ends tracking of all servers.
+func (t *peerTracker) stop() {
+	close(t.quit)
+	t.wg.Wait()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for sub := range t.subs {
+		close(sub)
+	}
+	t.subs = nil
+}
+
+// PeersInfo returns the connected peers of the current and, during a key
+// rotation, the retiring identity.
+func (n *Node) PeersInfo() []*GuardianPeerInfo {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	var peers []*p2p.Peer
+	for _, server := range []p2p.Server{n.server, n.retiring} {
+		if server != nil {
+			peers = append(peers, server.Peers()...)
+		}
+	}
+
+	var (
+		authorized = nodeIDSet(n.config.AuthorizedNodes)
+		trusted    = nodeIDSet(n.trustedNodes())
+		static     = nodeIDSet(n.config.serverConfig.StaticNodes)
+		infos      = make([]*GuardianPeerInfo, 0, len(peers))
+	)
+	if n.staticPeers != nil {
+		for id := range nodeIDSet(n.staticPeers.Nodes()) {
+			static[id] = true
+		}
+	}
+	for _, peer := range peers {
+		id := peer.ID()
+		info := &GuardianPeerInfo{
+			PeerInfo: peer.Info(),
+			Side:     PeerSidePublic,
+			NodeType: nodeTypeName(peer.ConnType()),
+			Static:   static[id],
+			Trusted:  trusted[id],
+		}
+		if authorized[id] {
+			info.Side = PeerSideAuthorized
+		}
+		if n.tracker != nil {
+			if tracked := n.tracker.peer(id); tracked != nil {
+				info.ConnectedAt = tracked.connectedAt
+				info.ConnectedFor = time.Since(tracked.connectedAt).Round(time.Second).String()
+				info.Traffic = tracked.traffic
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func nodeIDSet(nodes []*discover.Node) map[discover.NodeID]bool {
+	set := make(map[discover.NodeID]bool, len(nodes))
+	for _, node := range nodes {
+		set[node.ID] = true
+	}
+	return set
+}
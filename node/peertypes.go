@@ -0,0 +1,238 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klaytn/klaytn/networks/p2p"
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+// peerTypeSweepInterval is how often all public peers are checked against the
+// policy, to catch peers whose connect event was missed.
+const peerTypeSweepInterval = 10 * time.Second
+
+// Node type names reported in admin_peers and used by the peer type policy.
+var peerTypeNames = []string{"cn", "pn", "en", "bn", "unknown"}
+
+// PeerTypePolicy restricts the node types of the public peers. Authorized and
+// trusted peers are exempt.
+type PeerTypePolicy struct {
+	Allowed    map[string]bool // Allowed node types, nil = all
+	Max        map[string]int  // Maximum number of peers per node type
+	ReservedCN int             // Connections only consensus nodes may use
+	MaxPeers   int             // Maximum number of connections
+}
+
+// NewPeerTypePolicy builds the policy from the comma separated allowed node
+// types and the per type maximums.
+func NewPeerTypePolicy(allowed string, maxCN, maxPN, maxEN, reservedCN, maxPeers int) (*PeerTypePolicy, error) {
+	policy := &PeerTypePolicy{
+		Max:        map[string]int{"cn": maxCN, "pn": maxPN, "en": maxEN},
+		ReservedCN: reservedCN,
+		MaxPeers:   maxPeers,
+	}
+	// An empty list allows all types; empty entries are ignored
+	for _, t := range SplitAndTrim(allowed) {
+		if t == "" {
+			continue
+		}
+		if !isPeerType(t) {
+			return nil, fmt.Errorf("unknown node type %q, expected one of %v", t, peerTypeNames)
+		}
+		if policy.Allowed == nil {
+			policy.Allowed = make(map[string]bool)
+		}
+		policy.Allowed[t] = true
+	}
+	for t, max := range policy.Max {
+		if max < 0 {
+			return nil, fmt.Errorf("negative maximum of %s peers", t)
+		}
+	}
+	if reservedCN < 0 || (maxPeers > 0 && reservedCN > maxPeers) {
+		return nil, fmt.Errorf("reserved consensus node connections %d out of range [0, %d]", reservedCN, maxPeers)
+	}
+	return policy, nil
+}
+
+func isPeerType(name string) bool {
+	for _, t := range peerTypeNames {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// restricts reports whether the policy limits anything at all.
+func (p *PeerTypePolicy) restricts() bool {
+	if p.Allowed != nil || p.ReservedCN > 0 {
+		return true
+	}
+	for _, max := range p.Max {
+		if max > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns why a new public peer of the given type violates the policy,
+// given the number of public peers per type including the new one.
+func (p *PeerTypePolicy) check(nodeType string, counts map[string]int) (p2p.DiscReason, error) {
+	if p.Allowed != nil && !p.Allowed[nodeType] {
+		return p2p.DiscUselessPeer, fmt.Errorf("node type %s is not allowed", nodeType)
+	}
+	if max := p.Max[nodeType]; max > 0 && counts[nodeType] > max {
+		return p2p.DiscTooManyPeers, fmt.Errorf("more than %d %s peers", max, nodeType)
+	}
+	if nodeType != "cn" && p.ReservedCN > 0 && p.MaxPeers > 0 {
+		others := 0
+		for t, count := range counts {
+			if t != "cn" {
+				others += count
+			}
+		}
+		if others > p.MaxPeers-p.ReservedCN {
+			return p2p.DiscTooManyPeers, fmt.Errorf("the remaining %d connections are reserved for consensus nodes", p.ReservedCN)
+		}
+	}
+	return 0, nil
+}
+
+// setPeerTypePolicy builds the peer type policy from the options. The policy
+// is left unset if it does not restrict anything.
+func (cfg *GuardianConfig) setPeerTypePolicy() error {
+	policy, err := NewPeerTypePolicy(cfg.allowedPeerTypes, cfg.maxCNPeers, cfg.maxPNPeers, cfg.maxENPeers,
+		cfg.reservedCNPeers, cfg.serverConfig.MaxPhysicalConnections)
+	if err != nil {
+		return err
+	}
+	if policy.restricts() {
+		cfg.peerTypePolicy = policy
+	}
+	return nil
+}
+
+// peerTypeEnforcer disconnects new public peers which violate the policy.
+type peerTypeEnforcer struct {
+	node    *Node
+	policy  *PeerTypePolicy
+	tracker *peerTracker
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newPeerTypeEnforcer(node *Node, policy *PeerTypePolicy, tracker *peerTracker) *peerTypeEnforcer {
+	e := &peerTypeEnforcer{
+		node:    node,
+		policy:  policy,
+		tracker: tracker,
+		quit:    make(chan struct{}),
+	}
+	_, events := tracker.subscribe(func(event *GuardianPeerEvent) bool {
+		return event.Side == PeerSidePublic && event.Type == p2p.PeerEventTypeAdd
+	})
+	e.wg.Add(1)
+	go e.loop(events)
+	return e
+}
+
+func (e *peerTypeEnforcer) loop(events chan *GuardianPeerEvent) {
+	defer e.wg.Done()
+	defer e.tracker.unsubscribe(events)
+
+	// The tracker drops events for slow subscribers, so a connection flood
+	// can hide new peers from the enforcer. The sweep catches those.
+	sweep := time.NewTicker(peerTypeSweepInterval)
+	defer sweep.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			e.enforce(event.Peer)
+		case <-sweep.C:
+			e.sweep()
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+// enforce checks a newly connected public peer against the policy.
+func (e *peerTypeEnforcer) enforce(id discover.NodeID) {
+	trusted := nodeIDSet(e.node.TrustedPeers())
+	if trusted[id] {
+		return
+	}
+	var (
+		peer   *p2p.Peer
+		counts = make(map[string]int)
+	)
+	for _, server := range e.node.servers() {
+		for _, p := range server.Peers() {
+			if trusted[p.ID()] {
+				continue
+			}
+			counts[nodeTypeName(p.ConnType())]++
+			if p.ID() == id {
+				peer = p
+			}
+		}
+	}
+	if peer == nil {
+		return // Already gone
+	}
+	nodeType := nodeTypeName(peer.ConnType())
+	if reason, err := e.policy.check(nodeType, counts); err != nil {
+		e.node.logger.Info("Rejecting peer by node type policy", "id", id, "type", nodeType, "err", err)
+		peer.Disconnect(reason)
+	}
+}
+
+// sweep checks all public peers against the policy, admitting them one by
+// one and disconnecting those that violate it.
+func (e *peerTypeEnforcer) sweep() {
+	trusted := nodeIDSet(e.node.TrustedPeers())
+	counts := make(map[string]int)
+	for _, server := range e.node.servers() {
+		for _, peer := range server.Peers() {
+			if trusted[peer.ID()] {
+				continue
+			}
+			nodeType := nodeTypeName(peer.ConnType())
+			counts[nodeType]++
+			if reason, err := e.policy.check(nodeType, counts); err != nil {
+				counts[nodeType]--
+				e.node.logger.Info("Rejecting peer by node type policy", "id", peer.ID(), "type", nodeType, "err", err)
+				peer.Disconnect(reason)
+			}
+		}
+	}
+}
+
+func (e *peerTypeEnforcer) stop() {
+	close(e.quit)
+	e.wg.Wait()
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/klaytn/klaytn/networks/p2p"
+)
+
+func TestNewPeerTypePolicy(t *testing.T) {
+	tests := []struct {
+		allowed    string
+		max        [3]int // cn, pn, en
+		reservedCN int
+		maxPeers   int
+		want       map[string]bool // Allowed types if valid
+		restricts  bool
+		ok         bool
+	}{
+		{allowed: "", ok: true},
+		{allowed: "  ", ok: true},
+		{allowed: " , ,", ok: true},
+		{allowed: "cn", want: map[string]bool{"cn": true}, restricts: true, ok: true},
+		{allowed: " cn, ,pn,", want: map[string]bool{"cn": true, "pn": true}, restricts: true, ok: true},
+		{allowed: "cn,xn"},
+		{allowed: "CN"},
+		{max: [3]int{0, 5, 0}, restricts: true, ok: true},
+		{max: [3]int{0, -1, 0}},
+		{reservedCN: 10, maxPeers: 50, restricts: true, ok: true},
+		{reservedCN: 60, maxPeers: 50},
+		{reservedCN: -1},
+	}
+	for _, test := range tests {
+		policy, err := NewPeerTypePolicy(test.allowed, test.max[0], test.max[1], test.max[2], test.reservedCN, test.maxPeers)
+		if (err == nil) != test.ok {
+			t.Errorf("%+v: error %v, want ok %v", test, err, test.ok)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(policy.Allowed, test.want) {
+			t.Errorf("%q: allowed %v, want %v", test.allowed, policy.Allowed, test.want)
+		}
+		if policy.restricts() != test.restricts {
+			t.Errorf("%+v: restricts %v, want %v", test, policy.restricts(), test.restricts)
+		}
+	}
+}
+
+func TestPeerTypePolicyCheck(t *testing.T) {
+	tests := []struct {
+		policy   *PeerTypePolicy
+		nodeType string
+		counts   map[string]int // Including the new peer
+		reason   p2p.DiscReason // 0 if the peer is accepted
+	}{
+		// No allow list: all types are allowed
+		{&PeerTypePolicy{}, "en", map[string]int{"en": 1}, 0},
+		{&PeerTypePolicy{}, "unknown", map[string]int{"unknown": 1}, 0},
+		// Allow list
+		{&PeerTypePolicy{Allowed: map[string]bool{"cn": true, "pn": true}}, "pn", map[string]int{"pn": 1}, 0},
+		{&PeerTypePolicy{Allowed: map[string]bool{"cn": true, "pn": true}}, "en", map[string]int{"en": 1}, p2p.DiscUselessPeer},
+		// Per type quotas
+		{&PeerTypePolicy{Max: map[string]int{"en": 2}}, "en", map[string]int{"en": 2}, 0},
+		{&PeerTypePolicy{Max: map[string]int{"en": 2}}, "en", map[string]int{"en": 3}, p2p.DiscTooManyPeers},
+		{&PeerTypePolicy{Max: map[string]int{"en": 2}}, "pn", map[string]int{"en": 2, "pn": 10}, 0},
+		{&PeerTypePolicy{Max: map[string]int{"en": 0}}, "en", map[string]int{"en": 100}, 0}, // 0 = unlimited
+		// Connections reserved for consensus nodes
+		{&PeerTypePolicy{ReservedCN: 2, MaxPeers: 5}, "pn", map[string]int{"pn": 2, "en": 1}, 0},
+		{&PeerTypePolicy{ReservedCN: 2, MaxPeers: 5}, "en", map[string]int{"pn": 2, "en": 2}, p2p.DiscTooManyPeers},
+		{&PeerTypePolicy{ReservedCN: 2, MaxPeers: 5}, "cn", map[string]int{"pn": 3, "cn": 2}, 0},
+	}
+	for i, test := range tests {
+		reason, err := test.policy.check(test.nodeType, test.counts)
+		if reason != test.reason || (err == nil) != (test.reason == 0) {
+			t.Errorf("test %d: %s peer with %v: reason %v (err %v), want %v", i, test.nodeType, test.counts, reason, err, test.reason)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+
+	"github.com/klaytn/guardian/flags"
+	"github.com/klaytn/klaytn/cmd/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// NetworkPreset is a set of network parameters selected by --cypress, --baobab
+// or --devnet. Explicitly given options take precedence over the preset.
+//
+// The guardian runs no klay protocol, so the network id is not sent to peers:
+// it is only checked against the preset and reported by config dump.
+type NetworkPreset struct {
+	Name      string
+	NetworkID uint64
+	Port      int
+	SubPort   int
+
+	// UseBootnodes tells whether the network has public bootnodes. Their
+	// addresses are resolved from the same flags by utils.SetP2PConfig.
+	UseBootnodes bool
+}
+
+var (
+	CypressPreset = &NetworkPreset{
+		Name:         "cypress",
+		NetworkID:    8217,
+		Port:         32323,
+		SubPort:      32324,
+		UseBootnodes: true,
+	}
+	BaobabPreset = &NetworkPreset{
+		Name:         "baobab",
+		NetworkID:    1001,
+		Port:         32323,
+		SubPort:      32324,
+		UseBootnodes: true,
+	}
+	DevnetPreset = &NetworkPreset{
+		Name:      "devnet",
+		NetworkID: 2019,
+		Port:      32323,
+		SubPort:   32324,
+	}
+)
+
+// selectedPresets returns the presets enabled on the command line or in the
+// yaml file. More than one is rejected by ValidateNetworkParameter.
+func selectedPresets(ctx *cli.Context) []*NetworkPreset {
+	var presets []*NetworkPreset
+	if ctx.Bool(utils.CypressFlag.Name) {
+		presets = append(presets, CypressPreset)
+	}
+	if ctx.Bool(utils.BaobabFlag.Name) {
+		presets = append(presets, BaobabPreset)
+	}
+	if ctx.Bool(flags.DevnetFlag.Name) {
+		presets = append(presets, DevnetPreset)
+	}
+	return presets
+}
+
+// SetNetworkPreset applies the selected network preset to the options which
+// are not given explicitly. It has to run after SetP2PConfig.
+func SetNetworkPreset(ctx *cli.Context, cfg *GuardianConfig) {
+	cfg.presets = selectedPresets(ctx)
+	cfg.networkIDSet = ctx.IsSet(utils.NetworkIdFlag.Name)
+	if len(cfg.presets) != 1 {
+		return
+	}
+	preset := cfg.presets[0]
+
+	if !cfg.networkIDSet {
+		cfg.networkID = preset.NetworkID
+	}
+	if !ctx.IsSet(utils.ListenPortFlag.Name) {
+		cfg.serverConfig.ListenAddr = fmt.Sprintf(":%d", preset.Port)
+	}
+	if cfg.serverConfig.EnableMultiChannelServer && !ctx.IsSet(utils.SubListenPortFlag.Name) {
+		cfg.serverConfig.SubListenAddr = []string{fmt.Sprintf(":%d", preset.SubPort)}
+	}
+	if !preset.UseBootnodes && !ctx.IsSet(utils.BootnodesFlag.Name) {
+		cfg.serverConfig.BootstrapNodes = nil
+	}
+}
+
+// Preset returns the network preset in use, or nil if there is none.
+func (cfg *GuardianConfig) Preset() *NetworkPreset {
+	if len(cfg.presets) != 1 {
+		return nil
+	}
+	return cfg.presets[0]
+}
+
+// validatePreset rejects multiple presets and a network id which does not
+// match the preset.
+func (cfg *GuardianConfig) validatePreset() error {
+	if len(cfg.presets) > 1 {
+		return fmt.Errorf("options --%s, --%s and --%s are mutually exclusive",
+			utils.CypressFlag.Name, utils.BaobabFlag.Name, flags.DevnetFlag.Name)
+	}
+	if preset := cfg.Preset(); preset != nil && cfg.networkIDSet && cfg.networkID != preset.NetworkID {
+		return fmt.Errorf("network id %d does not match the %s network id %d", cfg.networkID, preset.Name, preset.NetworkID)
+	}
+	return nil
+}
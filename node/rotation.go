@@ -0,0 +1,131 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/klaytn/klaytn/networks/p2p"
+)
+
+// DefaultRotationGracePeriod is how long the old identity keeps serving its
+// connections after a key rotation if no grace period is given.
+const DefaultRotationGracePeriod = time.Minute
+
+var (
+	ErrRotationInProgress = errors.New("node key rotation already in progress")
+	ErrRotationRepeated   = errors.New("node key already rotated, restart the guardian before rotating again")
+	ErrRotationHexKey     = errors.New("node key given by --nodekeyhex can not be rotated, use --nodekey or --nodekeystore")
+	ErrRotationNoPort     = errors.New("node key rotation requires --rotationport, the port the new identity listens on until restart")
+)
+
+// RotationInfo describes the result of a node key rotation.
+type RotationInfo struct {
+	Old       string    `json:"old"`       // kni of the retiring identity
+	New       string    `json:"new"`       // kni of the new identity
+	KeyPath   string    `json:"keyPath"`   // File the new key was written to
+	Backup    string    `json:"backup"`    // Copy of the old key, for a rollback
+	RetiresAt time.Time `json:"retiresAt"` // When the old identity is shut down
+}
+
+// RotateNodeKey generates a new node key and starts a second p2p server with it
+// next to the running one. The new server dials the authorized nodes, so they
+// learn the new identity, and the old server is stopped after the grace period.
+//
+// The new server keeps listening on --rotationport until the guardian is
+// restarted with the new key on the configured port, so the key can only be
+// rotated once per run.
+func (n *Node) RotateNodeKey(grace time.Duration) (*RotationInfo, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.server == nil {
+		return nil, ErrNodeStopped
+	}
+	if n.retiring != nil {
+		return nil, ErrRotationInProgress
+	}
+	if n.rotated {
+		return nil, ErrRotationRepeated
+	}
+	if n.config.nodeKeyHex != "" {
+		return nil, ErrRotationHexKey
+	}
+	if n.config.rotationPort == 0 {
+		return nil, ErrRotationNoPort
+	}
+
+	key, err := newNodeKey("")
+	if err != nil {
+		return nil, err
+	}
+
+	serverConfig := n.config.serverConfig
+	serverConfig.PrivateKey = key
+	serverConfig.ListenAddr = fmt.Sprintf(":%d", n.config.rotationPort)
+	serverConfig.SubListenAddr = n.config.rotationSubListenAddr()
+	serverConfig.StaticNodes = mergeNodes(serverConfig.StaticNodes, n.staticPeers.Nodes(), n.config.AuthorizedNodes)
+	serverConfig.TrustedNodes = n.trustedNodes()
+	server := p2p.NewServer(serverConfig)
+	if err := server.Start(); err != nil {
+		return nil, convertFileLockError(err)
+	}
+
+	// Only persist the key once the new identity is up, so a failed rotation
+	// leaves the configured key untouched.
+	keyPath, backup, err := n.config.persistNodeKey(key)
+	if err != nil {
+		server.Stop()
+		return nil, err
+	}
+
+	n.tracker.track(server)
+
+	old := n.server
+	n.retiring = old
+	n.server = server
+	n.rotated = true
+	n.config.nodeKey = key
+	n.config.serverConfig.PrivateKey = key
+
+	info := &RotationInfo{
+		Old:       old.Self().String(),
+		New:       server.Self().String(),
+		KeyPath:   keyPath,
+		Backup:    backup,
+		RetiresAt: time.Now().Add(grace),
+	}
+	n.logger.Info("Rotated node key", "old", info.Old, "new", info.New, "key", keyPath, "backup", backup, "grace", grace)
+
+	time.AfterFunc(grace, func() { n.retireServer(old) })
+	return info, nil
+}
+
+// retireServer stops the p2p server of a rotated out identity.
+func (n *Node) retireServer(server p2p.Server) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.retiring != server {
+		return
+	}
+	server.Stop()
+	n.retiring = nil
+	n.logger.Info("Retired old node identity", "kni", server.Self().String())
+}
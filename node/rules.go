@@ -0,0 +1,330 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ruleEvalInterval = 5 * time.Second
+	ruleStartGrace   = time.Minute // Time for the peers to connect before rules may fire
+)
+
+// alertMetrics are the metrics alert rules can refer to. Counters, marked
+// true, can also be used as <name>_rate, their increase per second.
+var alertMetrics = map[string]bool{
+	"peers":            false,
+	"authorized_peers": false,
+	"public_peers":     false,
+	"peer_connects":    true,
+	"peer_disconnects": true,
+	"messages_in":      true,
+	"messages_out":     true,
+	"bytes_in":         true,
+	"bytes_out":        true,
+	"dropped_events":   true,
+}
+
+// Alert states reported by admin_alerts.
+const (
+	AlertPending = "pending" // The condition holds, but not for long enough yet
+	AlertFiring  = "firing"
+)
+
+// AlertRule is a condition on a guardian metric, written as
+//
+//	[critical:|warning:] <metric> <op> <value>[/s] [for <duration>]
+//
+// e.g. "critical: authorized_peers < 1 for 30s" or "peer_disconnects_rate > 2/s".
+// The alert fires once the condition held for the duration and is resolved
+// when it no longer holds. No rule fires during the first minute after the
+// start, while the peers are still connecting.
+type AlertRule struct {
+	Text      string
+	Severity  string
+	Metric    string
+	Rate      bool // Metric is the per second increase of a counter
+	Op        string
+	Threshold float64
+	For       time.Duration
+}
+
+// ParseAlertRule parses an alert rule.
+func ParseAlertRule(text string) (*AlertRule, error) {
+	rule := &AlertRule{Text: strings.TrimSpace(text), Severity: AlertWarning}
+	expr := rule.Text
+	for _, severity := range []string{AlertCritical, AlertWarning} {
+		if strings.HasPrefix(expr, severity+":") {
+			rule.Severity = severity
+			expr = strings.TrimSpace(strings.TrimPrefix(expr, severity+":"))
+		}
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 3 && (len(fields) != 5 || fields[3] != "for") {
+		return nil, fmt.Errorf("invalid alert rule %q, expected \"<metric> <op> <value> [for <duration>]\"", text)
+	}
+
+	rule.Metric = fields[0]
+	name := strings.TrimSuffix(rule.Metric, "_rate")
+	counter, ok := alertMetrics[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q in alert rule %q, known metrics: %s", rule.Metric, text, metricNames())
+	}
+	if rule.Rate = name != rule.Metric; rule.Rate && !counter {
+		return nil, fmt.Errorf("metric %s in alert rule %q is not a counter and has no rate", name, text)
+	}
+
+	switch rule.Op = fields[1]; rule.Op {
+	case "<", "<=", ">", ">=", "==", "!=":
+	default:
+		return nil, fmt.Errorf("unknown operator %q in alert rule %q", rule.Op, text)
+	}
+
+	value := fields[2]
+	if strings.HasSuffix(value, "/s") {
+		if !rule.Rate {
+			return nil, fmt.Errorf("per second value %s in alert rule %q needs a _rate metric", value, text)
+		}
+		value = strings.TrimSuffix(value, "/s")
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q in alert rule %q", fields[2], text)
+	}
+	rule.Threshold = threshold
+
+	if len(fields) == 5 {
+		if rule.For, err = time.ParseDuration(fields[4]); err != nil || rule.For < 0 {
+			return nil, fmt.Errorf("invalid duration %q in alert rule %q", fields[4], text)
+		}
+	}
+	return rule, nil
+}
+
+func metricNames() string {
+	names := make([]string, 0, len(alertMetrics))
+	for name, counter := range alertMetrics {
+		names = append(names, name)
+		if counter {
+			names = append(names, name+"_rate")
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// name is the alert name of the rule: the condition without the severity.
+func (r *AlertRule) name() string {
+	name := fmt.Sprintf("%s %s %v", r.Metric, r.Op, r.Threshold)
+	if r.For > 0 {
+		name += " for " + r.For.String()
+	}
+	return name
+}
+
+func (r *AlertRule) holds(value float64) bool {
+	switch r.Op {
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "==":
+		return value == r.Threshold
+	}
+	return value != r.Threshold
+}
+
+// ActiveAlert is a rule whose condition currently holds.
+type ActiveAlert struct {
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	State    string    `json:"state"`
+	Since    time.Time `json:"since"` // When the condition started to hold
+	Value    float64   `json:"value"`
+}
+
+// ruleState is the evaluation state of a rule.
+type ruleState struct {
+	since  time.Time // Zero while the condition does not hold
+	firing bool
+	value  float64
+}
+
+// ruleEngine evaluates the alert rules against the peer tracker metrics. An
+// alert is sent once when a rule fires and once when it is resolved.
+type ruleEngine struct {
+	rules   []*AlertRule
+	states  []ruleState
+	tracker *peerTracker
+	alerter *Alerter  // nil if no webhook is configured
+	started time.Time // Rules fire no earlier than ruleStartGrace after this
+
+	prev     map[string]float64 // Metrics of the previous evaluation, for rates
+	prevTime time.Time
+
+	lock sync.Mutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// parseAlertRules parses the configured rules. Repeated rules of the same
+// severity are ignored.
+func parseAlertRules(config AlertConfig) ([]*AlertRule, error) {
+	texts := config.Rules
+	if config.MinPublicPeers > 0 {
+		texts = append(texts, fmt.Sprintf("public_peers < %d", config.MinPublicPeers))
+	}
+	var (
+		rules []*AlertRule
+		seen  = make(map[string]bool)
+	)
+	for _, text := range texts {
+		rule, err := ParseAlertRule(text)
+		if err != nil {
+			return nil, err
+		}
+		if key := rule.Severity + ": " + rule.name(); !seen[key] {
+			seen[key] = true
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func newRuleEngine(rules []*AlertRule, tracker *peerTracker, alerter *Alerter) *ruleEngine {
+	e := &ruleEngine{
+		rules:   rules,
+		states:  make([]ruleState, len(rules)),
+		tracker: tracker,
+		alerter: alerter,
+		started: time.Now(),
+		quit:    make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.loop()
+	return e
+}
+
+func (e *ruleEngine) loop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(ruleEvalInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			e.evaluate(now)
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+// evaluate updates the state of every rule and sends the resulting alerts.
+func (e *ruleEngine) evaluate(now time.Time) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	metrics := e.tracker.metrics()
+	prev, elapsed := e.prev, now.Sub(e.prevTime).Seconds()
+	e.prev, e.prevTime = metrics, now
+
+	for i, rule := range e.rules {
+		state := &e.states[i]
+		name := strings.TrimSuffix(rule.Metric, "_rate")
+		value := metrics[name]
+		if rule.Rate {
+			if prev == nil || elapsed <= 0 {
+				continue
+			}
+			value = (value - prev[name]) / elapsed
+		}
+		state.value = value
+
+		if !rule.holds(value) {
+			if state.firing {
+				e.send(rule, value, true)
+			}
+			*state = ruleState{value: value}
+			continue
+		}
+		if state.since.IsZero() {
+			state.since = now
+		}
+		if !state.firing && now.Sub(state.since) >= rule.For && now.Sub(e.started) >= ruleStartGrace {
+			state.firing = true
+			e.send(rule, value, false)
+		}
+	}
+}
+
+func (e *ruleEngine) send(rule *AlertRule, value float64, resolved bool) {
+	logger.Info("Alert rule changed state", "rule", rule.Text, "value", value, "resolved", resolved)
+	if e.alerter == nil {
+		return
+	}
+	e.alerter.Send(&Alert{
+		Name:     rule.name(),
+		Key:      rule.Severity,
+		Severity: rule.Severity,
+		Summary:  fmt.Sprintf("%s is %v (rule: %s)", rule.Metric, value, rule.name()),
+		Details:  map[string]interface{}{"metric": rule.Metric, "value": value, "threshold": rule.Threshold},
+		Resolved: resolved,
+	})
+}
+
+// active returns the rules whose condition currently holds.
+func (e *ruleEngine) active() []*ActiveAlert {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	alerts := []*ActiveAlert{}
+	for i, rule := range e.rules {
+		state := e.states[i]
+		if state.since.IsZero() {
+			continue
+		}
+		alert := &ActiveAlert{
+			Rule:     rule.Text,
+			Severity: rule.Severity,
+			State:    AlertPending,
+			Since:    state.since,
+			Value:    state.value,
+		}
+		if state.firing {
+			alert.State = AlertFiring
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+func (e *ruleEngine) stop() {
+	close(e.quit)
+	e.wg.Wait()
+}
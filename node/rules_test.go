@@ -0,0 +1,196 @@
+// Copyright 2023 The klaytn Authors
+// This file is part of the klaytn library.
+//
+// The klaytn library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The klaytn library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the klaytn library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/klaytn/klaytn/networks/p2p/discover"
+)
+
+func TestParseAlertRule(t *testing.T) {
+	tests := []struct {
+		text string
+		want *AlertRule // nil if the rule is invalid
+	}{
+		{
+			text: "public_peers < 3",
+			want: &AlertRule{Text: "public_peers < 3", Severity: AlertWarning, Metric: "public_peers", Op: "<", Threshold: 3},
+		},
+		{
+			text: " critical: authorized_peers < 1 for 30s ",
+			want: &AlertRule{Text: "critical: authorized_peers < 1 for 30s", Severity: AlertCritical, Metric: "authorized_peers", Op: "<", Threshold: 1, For: 30 * time.Second},
+		},
+		{
+			text: "warning: peer_disconnects_rate > 2/s",
+			want: &AlertRule{Text: "warning: peer_disconnects_rate > 2/s", Severity: AlertWarning, Metric: "peer_disconnects_rate", Rate: true, Op: ">", Threshold: 2},
+		},
+		{
+			text: "bytes_in_rate >= 1.5e6",
+			want: &AlertRule{Text: "bytes_in_rate >= 1.5e6", Severity: AlertWarning, Metric: "bytes_in_rate", Rate: true, Op: ">=", Threshold: 1.5e6},
+		},
+		{
+			text: "peers != 0 for 1m",
+			want: &AlertRule{Text: "peers != 0 for 1m", Severity: AlertWarning, Metric: "peers", Op: "!=", Threshold: 0, For: time.Minute},
+		},
+		{text: ""},
+		{text: "peers <"},
+		{text: "peers < 1 during 30s"},
+		{text: "peers < 1 for"},
+		{text: "unknown < 1"},
+		{text: "peers_rate > 1"},       // Not a counter
+		{text: "peer_connects > 1/s"},  // Per second value without _rate
+		{text: "peers =~ 1"},           // Unknown operator
+		{text: "peers < one"},          // Not a number
+		{text: "peers < 1 for soon"},   // Not a duration
+		{text: "peers < 1 for -30s"},   // Negative duration
+		{text: "info: peers < 1"},      // Unknown severity
+		{text: "critical:peers<1 for"}, // Missing spaces
+	}
+	for _, test := range tests {
+		rule, err := ParseAlertRule(test.text)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %+v", test.text, rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(rule, test.want) {
+			t.Errorf("%q: got %+v, want %+v", test.text, rule, test.want)
+		}
+	}
+}
+
+func TestParseAlertRulesDedup(t *testing.T) {
+	rules, err := parseAlertRules(AlertConfig{
+		Rules: []string{
+			"public_peers < 2",
+			"warning: public_peers < 2",  // Same rule, dropped
+			"critical: public_peers < 2", // Other severity, kept
+		},
+		MinPublicPeers: 2, // Same as the first rule, dropped
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, rule := range rules {
+		texts = append(texts, rule.Text)
+	}
+	if want := []string{"public_peers < 2", "critical: public_peers < 2"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("got rules %q, want %q", texts, want)
+	}
+}
+
+func TestRuleEngineStates(t *testing.T) {
+	rule, err := ParseAlertRule("critical: peers < 1 for 30s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	tracker := newPeerTracker(nil, nil)
+	e := &ruleEngine{
+		rules:   []*AlertRule{rule},
+		states:  make([]ruleState, 1),
+		tracker: tracker,
+		started: start,
+	}
+	setPeers := func(n int) {
+		tracker.lock.Lock()
+		defer tracker.lock.Unlock()
+		tracker.peers = make(map[discover.NodeID]*trackedPeer)
+		for i := 0; i < n; i++ {
+			tracker.peers[discover.NodeID{byte(i + 1)}] = &trackedPeer{conns: 1}
+		}
+	}
+
+	tests := []struct {
+		at    time.Duration // Since the start
+		peers int
+		state string // Empty if the rule is not active
+	}{
+		{0, 0, AlertPending},
+		{40 * time.Second, 0, AlertPending}, // Held for 30s, but within the start grace
+		{time.Minute, 0, AlertFiring},
+		{65 * time.Second, 1, ""}, // Resolved
+		{70 * time.Second, 0, AlertPending},
+		{80 * time.Second, 1, ""}, // Cleared before it fired
+		{90 * time.Second, 0, AlertPending},
+		{119 * time.Second, 0, AlertPending},
+		{120 * time.Second, 0, AlertFiring},
+		{125 * time.Second, 0, AlertFiring},
+	}
+	for i, test := range tests {
+		setPeers(test.peers)
+		e.evaluate(start.Add(test.at))
+
+		active := e.active()
+		state := ""
+		if len(active) > 0 {
+			state = active[0].State
+		}
+		if state != test.state {
+			t.Errorf("step %d (%v, %d peers): state %q, want %q", i, test.at, test.peers, state, test.state)
+		}
+	}
+}
+
+func TestRuleEngineRate(t *testing.T) {
+	rule, err := ParseAlertRule("peer_disconnects_rate > 1/s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-ruleStartGrace)
+	tracker := newPeerTracker(nil, nil)
+	e := &ruleEngine{
+		rules:   []*AlertRule{rule},
+		states:  make([]ruleState, 1),
+		tracker: tracker,
+		started: start,
+	}
+	steps := []struct {
+		at          time.Duration
+		disconnects uint64
+		state       string
+	}{
+		{ruleStartGrace, 0, ""},                  // No rate without a previous evaluation
+		{ruleStartGrace + 10*time.Second, 5, ""}, // 0.5/s
+		{ruleStartGrace + 20*time.Second, 30, AlertFiring},
+		{ruleStartGrace + 30*time.Second, 35, ""},
+	}
+	for i, step := range steps {
+		tracker.lock.Lock()
+		tracker.disconnects = step.disconnects
+		tracker.lock.Unlock()
+		e.evaluate(start.Add(step.at))
+
+		active := e.active()
+		state := ""
+		if len(active) > 0 {
+			state = active[0].State
+		}
+		if state != step.state {
+			t.Errorf("step %d: state %q, want %q", i, state, step.state)
+		}
+	}
+}